@@ -12,14 +12,20 @@ import (
 
 	"github.com/alecthomas/kong"
 	"github.com/joho/godotenv"
+	"github.com/kklipsch/billy-bot/pkg/dev"
 	"github.com/kklipsch/billy-bot/pkg/frinkiac"
+	"github.com/kklipsch/billy-bot/pkg/openrouter"
 	"github.com/kklipsch/billy-bot/pkg/smee"
 )
 
 // CLI represents the command-line interface structure for the application
 type CLI struct {
-	Smee     smee.Command     `cmd:"smee" help:"Run the Smee client to receive webhook events."`
-	Frinkiac frinkiac.Command `cmd:"frinkiac" help:"Engage the frinkac tool to find Simpsons scenes."`
+	Smee       smee.Command             `cmd:"smee" help:"Run the Smee client to receive webhook events."`
+	Frinkiac   frinkiac.Command         `cmd:"frinkiac" help:"Engage the frinkac tool to find Simpsons scenes."`
+	OpenRouter openrouter.Command       `cmd:"" name:"openrouter" help:"Talk to OpenRouter models directly."`
+	Models     openrouter.ModelsCommand `cmd:"models" help:"Browse the models available through OpenRouter."`
+	Cache      frinkiac.CacheCommand    `cmd:"cache" help:"Manage the cache of Frinkiac responses."`
+	Dev        dev.Command              `cmd:"dev" help:"Tools for developing billy-bot offline."`
 
 	EnvFile  string `default:".env" name:"env-file" short:"e" help:"Path to the .env file to load. Defaults to .env in the current directory. Set explicitly to empty to skip loading."`
 	LogLevel string `default:"warn" name:"log-level" short:"l" help:"Set the log level. Options: debug, info, warn, error, fatal, panic. Defaults to warn."`
@@ -0,0 +1,326 @@
+// Package cassette records HTTP interactions to a file and replays them so tests that talk to
+// OpenRouter or Frinkiac can run offline and deterministically.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ModeEnvVar names the environment variable that ModeFromEnv reads
+const ModeEnvVar = "BILLY_BOT_CASSETTE"
+
+// ErrNoInteraction is returned by a replaying Transport when no recorded interaction matches a request
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// Mode controls whether a Transport replays or records interactions
+type Mode int
+
+const (
+	// ModeReplay serves requests from the cassette and never touches the network
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the real transport and saves the interactions to the cassette
+	ModeRecord
+)
+
+// ModeFromEnv returns ModeRecord if BILLY_BOT_CASSETTE is set to "record", otherwise ModeReplay
+func ModeFromEnv() Mode {
+	if os.Getenv(ModeEnvVar) == "record" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// DefaultScrubbedHeaders are the headers that are never written to a cassette
+var DefaultScrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Cassette is the on disk list of interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a request and the response it received
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is a recorded response
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// Load reads a cassette from path
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("error parsing cassette %s: %w", path, err)
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to path, creating its directory if needed
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("error creating cassette directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+
+	return nil
+}
+
+// Transport is an http.RoundTripper that replays or records the interactions in a cassette file.
+// Requests are matched on method, URL and body. Each recorded interaction is replayed once in
+// order, after which the last match is repeated so idempotent requests can be made again.
+type Transport struct {
+	// Path is the cassette file
+	Path string
+	// Mode is whether to replay or record
+	Mode Mode
+	// Real sends requests when recording, http.DefaultTransport if nil
+	Real http.RoundTripper
+	// ScrubbedHeaders are left out of the recorded requests and responses
+	ScrubbedHeaders []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// New creates a Transport for the cassette at path. In replay mode the cassette must already exist.
+func New(path string, mode Mode) (*Transport, error) {
+	t := &Transport{
+		Path:            path,
+		Mode:            mode,
+		ScrubbedHeaders: DefaultScrubbedHeaders,
+		cassette:        &Cassette{},
+	}
+
+	if mode == ModeReplay {
+		c, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		t.cassette = c
+		t.used = make([]bool, len(c.Interactions))
+	}
+
+	return t, nil
+}
+
+// Client returns an http.Client that uses the transport
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Mode == ModeRecord {
+		return t.record(req, body)
+	}
+
+	return t.replay(req, body)
+}
+
+// Save writes the recorded interactions to the cassette file, it does nothing when replaying
+func (t *Transport) Save() error {
+	if t.Mode != ModeRecord {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.cassette.Save(t.Path)
+}
+
+// replay finds the interaction matching the request
+func (t *Transport) replay(req *http.Request, body string) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := -1
+	for i, interaction := range t.cassette.Interactions {
+		if !matches(interaction.Request, req, body) {
+			continue
+		}
+
+		last = i
+		if !t.used[i] {
+			break
+		}
+	}
+
+	if last < 0 {
+		return nil, fmt.Errorf("%w: %s %s in %s", ErrNoInteraction, req.Method, req.URL, t.Path)
+	}
+
+	t.used[last] = true
+	log.Trace().Str("method", req.Method).Str("url", req.URL.String()).Int("interaction", last).Msg("replaying cassette interaction")
+
+	return t.cassette.Interactions[last].Response.toHTTP(req), nil
+}
+
+// record sends the request and keeps the interaction
+func (t *Transport) record(req *http.Request, body string) (*http.Response, error) {
+	next := t.Real
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	// a RoundTripper must not modify the request so send a copy with the body that was read
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(strings.NewReader(body))
+
+	resp, err := next.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response to record: %w", err)
+	}
+
+	interaction := Interaction{
+		Request: Request{
+			Method:  req.Method,
+			URL:     req.URL.String(),
+			Headers: t.scrub(req.Header),
+			Body:    body,
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Headers:    t.scrub(resp.Header),
+			Body:       string(respBody),
+		},
+	}
+
+	t.mu.Lock()
+	t.cassette.Interactions = append(t.cassette.Interactions, interaction)
+	t.mu.Unlock()
+
+	log.Debug().Str("method", req.Method).Str("url", req.URL.String()).Int("status", resp.StatusCode).Msg("recorded cassette interaction")
+
+	return interaction.Response.toHTTP(req), nil
+}
+
+// scrub copies headers without the scrubbed ones
+func (t *Transport) scrub(headers http.Header) http.Header {
+	scrubbed := headers.Clone()
+	for _, name := range t.ScrubbedHeaders {
+		scrubbed.Del(name)
+	}
+
+	if len(scrubbed) == 0 {
+		return nil
+	}
+	return scrubbed
+}
+
+// toHTTP builds the http.Response for a recorded response
+func (r Response) toHTTP(req *http.Request) *http.Response {
+	headers := r.Headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          io.NopCloser(bytes.NewBufferString(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// matches reports whether a recorded request matches req, comparing JSON bodies by value
+func matches(recorded Request, req *http.Request, body string) bool {
+	if recorded.Method != req.Method || recorded.URL != req.URL.String() {
+		return false
+	}
+
+	if recorded.Body == body {
+		return true
+	}
+
+	return equalJSON(recorded.Body, body)
+}
+
+// equalJSON reports whether a and b are the same JSON value, false if either is not JSON
+func equalJSON(a, b string) bool {
+	var av, bv any
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return false
+	}
+
+	ac, err := json.Marshal(av)
+	if err != nil {
+		return false
+	}
+	bc, err := json.Marshal(bv)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(ac, bc)
+}
+
+// readBody reads and closes the request body
+func readBody(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return "", nil
+	}
+	defer req.Body.Close()
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading request body: %w", err)
+	}
+
+	return string(data), nil
+}
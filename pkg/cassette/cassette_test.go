@@ -0,0 +1,90 @@
+package cassette
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRecordReplay tests that recorded interactions are scrubbed and replayed without the network
+func TestRecordReplay(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"), "The real request should keep its credentials")
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(r.Method + " " + string(body)))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+
+	recorder, err := New(path, ModeRecord)
+	require.NoError(t, err)
+
+	post := func(client *http.Client, body string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/chat", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		return client.Do(req)
+	}
+
+	for _, body := range []string{`{"n": 1}`, `{"n": 2}`} {
+		resp, err := post(recorder.Client(), body)
+		require.NoError(t, err)
+		got, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "POST "+body, string(got), "The real response should be returned while recording")
+	}
+	require.NoError(t, recorder.Save())
+
+	saved, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, saved.Interactions, 2)
+	assert.Empty(t, saved.Interactions[0].Request.Headers.Get("Authorization"), "Authorization should be scrubbed")
+	assert.Empty(t, saved.Interactions[0].Response.Headers.Get("Set-Cookie"), "Cookies should be scrubbed")
+
+	replayer, err := New(path, ModeReplay)
+	require.NoError(t, err)
+
+	// JSON bodies are matched by value so formatting differences don't matter
+	resp, err := post(replayer.Client(), `{"n":2}`)
+	require.NoError(t, err)
+	got, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `POST {"n": 2}`, string(got))
+	assert.Equal(t, 2, calls, "Replaying should not touch the network")
+
+	_, err = post(replayer.Client(), `{"n": 3}`)
+	assert.ErrorIs(t, err, ErrNoInteraction)
+}
+
+// TestReplayOrder tests that identical requests are replayed in recorded order and the last one repeats
+func TestReplayOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	c := &Cassette{}
+	for _, body := range []string{"first", "second"} {
+		c.Interactions = append(c.Interactions, Interaction{
+			Request:  Request{Method: http.MethodGet, URL: "https://frinkiac.com/api/random"},
+			Response: Response{StatusCode: http.StatusOK, Body: body},
+		})
+	}
+	require.NoError(t, c.Save(path))
+
+	transport, err := New(path, ModeReplay)
+	require.NoError(t, err)
+
+	var bodies []string
+	for range 3 {
+		resp, err := transport.Client().Get("https://frinkiac.com/api/random")
+		require.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		bodies = append(bodies, string(body))
+	}
+	assert.Equal(t, []string{"first", "second", "second"}, bodies)
+}
@@ -0,0 +1,84 @@
+package dev
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/fake"
+	openrouterfake "github.com/kklipsch/billy-bot/pkg/openrouter/fake"
+)
+
+// Command represents the CLI command group for offline development tools
+type Command struct {
+	FakeFrinkiac   FakeFrinkiacCommand   `cmd:"fake-frinkiac" help:"Serve a fake Frinkiac so the bot works without the internet."`
+	FakeOpenRouter FakeOpenRouterCommand `cmd:"fake-openrouter" help:"Serve a fake OpenRouter that answers from a script."`
+}
+
+// FakeFrinkiacCommand represents the fake-frinkiac subcommand for serving the fake Frinkiac
+type FakeFrinkiacCommand struct {
+	Addr          string        `default:"127.0.0.1:8089" help:"Address to listen on."`
+	Latency       time.Duration `help:"Latency added to every response, e.g. 250ms."`
+	ErrorRate     float64       `name:"error-rate" help:"Fraction of API responses, 0 to 1, that fail with a 500."`
+	MalformedRate float64       `name:"malformed-rate" help:"Fraction of API responses, 0 to 1, that return truncated JSON."`
+	Seed          uint64        `help:"Seed for the injected faults and random frames."`
+}
+
+// Run executes the fake-frinkiac command, serving until the context is cancelled
+func (c *FakeFrinkiacCommand) Run(ctx context.Context) error {
+	handler := fake.NewServer(fake.DefaultCorpus(), fake.Config{
+		Latency:       c.Latency,
+		ErrorRate:     c.ErrorRate,
+		MalformedRate: c.MalformedRate,
+		Seed:          c.Seed,
+	})
+
+	return serve(ctx, c.Addr, handler, "Serving fake frinkiac on http://%[1]s, use --frinkiac-url http://%[1]s\n")
+}
+
+// FakeOpenRouterCommand represents the fake-openrouter subcommand for serving the fake OpenRouter
+type FakeOpenRouterCommand struct {
+	Addr   string `default:"127.0.0.1:8090" help:"Address to listen on."`
+	Script string `type:"existingfile" help:"JSON or YAML script of the responses to send. Defaults to answering every request with a few quotes."`
+}
+
+// Run executes the fake-openrouter command, serving until the context is cancelled
+func (c *FakeOpenRouterCommand) Run(ctx context.Context) error {
+	script := openrouterfake.DefaultScript()
+	if c.Script != "" {
+		var err error
+		if script, err = openrouterfake.LoadScript(c.Script); err != nil {
+			return err
+		}
+	}
+
+	return serve(ctx, c.Addr, openrouterfake.NewServer(script), "Serving fake openrouter on http://%[1]s, use --openrouter-url http://%[1]s\n")
+}
+
+// serve runs handler on addr until the context is cancelled, announcing the address with format
+func serve(ctx context.Context, addr string, handler http.Handler, format string) error {
+	server := &http.Server{Addr: addr, Handler: handler}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", addr, err)
+	}
+
+	fmt.Printf(format, listener.Addr())
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
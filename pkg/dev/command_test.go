@@ -0,0 +1,41 @@
+package dev
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/ai"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/fake"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	openrouterfake "github.com/kklipsch/billy-bot/pkg/openrouter/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDefaultFakesAgree tests that the default script's quotes are found by the fake Frinkiac in the episodes the script names,
+// so running the bot against both fakes finds the scenes it is told about
+func TestDefaultFakesAgree(t *testing.T) {
+	server := httptest.NewServer(fake.NewServer(fake.DefaultCorpus(), fake.Config{}))
+	defer server.Close()
+
+	client := sites.NewClient(sites.WithBaseURL(server.URL), sites.WithLimiter(nil))
+
+	for _, exchange := range openrouterfake.DefaultScript().Exchanges {
+		var quotes []ai.QuoteResponse
+		require.NoError(t, json.Unmarshal([]byte(exchange.Content), &quotes))
+
+		for _, quote := range quotes {
+			results, err := client.Search(context.Background(), quote.Quote)
+			require.NoError(t, err)
+
+			var episodes []sites.EpisodeID
+			for _, result := range results {
+				episodes = append(episodes, result.EpisodID)
+			}
+			assert.Contains(t, episodes, sites.EpisodeID(fmt.Sprintf("S%02dE%02d", quote.Season, quote.Episode)), "%q should be found in the episode the script names", quote.Quote)
+		}
+	}
+}
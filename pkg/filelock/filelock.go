@@ -0,0 +1,67 @@
+// Package filelock coordinates billy-bot processes that share state files, e.g. the Frinkiac cache
+// and the OpenRouter budget, so concurrent runs don't overwrite each other's changes.
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lock takes an exclusive lock on path, waiting for any other process holding it.
+// The lock is held on a separate path+".lock" file so path itself can be replaced while locked.
+// Call the returned function to release it.
+func Lock(path string) (func() error, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("error creating directory for %s: %w", path, err)
+	}
+
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock for %s: %w", path, err)
+	}
+
+	if err := lock(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("error locking %s: %w", path, err)
+	}
+
+	return func() error {
+		err := unlock(f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}, nil
+}
+
+// WriteFile replaces path atomically with data. It writes to a uniquely named temporary file in the
+// same directory and renames it over path, so readers never see a partial file and concurrent writers
+// don't clobber each other's temporary files.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// a no-op once the rename has happened
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,46 @@
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLock tests that read-modify-write cycles under the lock don't lose updates
+func TestLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "count")
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := Lock(path)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer unlock()
+
+			count := 0
+			if data, err := os.ReadFile(path); err == nil {
+				count, _ = strconv.Atoi(string(data))
+			}
+			assert.NoError(t, WriteFile(path, []byte(strconv.Itoa(count+1)), 0o644))
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "20", string(data), "Every increment should be kept")
+
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, matches, "No temporary files should be left behind")
+}
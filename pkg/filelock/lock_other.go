@@ -0,0 +1,15 @@
+//go:build !unix
+
+package filelock
+
+import "os"
+
+// lock is a no-op where flock is unavailable, callers still serialise within the process
+func lock(*os.File) error {
+	return nil
+}
+
+// unlock is a no-op where flock is unavailable
+func unlock(*os.File) error {
+	return nil
+}
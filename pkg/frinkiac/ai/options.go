@@ -0,0 +1,66 @@
+package ai
+
+import (
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	openrouter "github.com/kklipsch/billy-bot/pkg/openrouter"
+)
+
+// Options are the model and generation parameters used when asking for candidate quotes.
+// Zero values are left out of the request so OpenRouter and the model defaults apply.
+// Fixing the Model and Seed makes it possible to reproduce a good run.
+type Options struct {
+	// Site is the site the quotes will be searched on and so which show they are from, Frinkiac if not set
+	Site sites.Site
+
+	// Model is the model to use, the client's model if empty
+	Model string
+	// Models are fallback models tried in order if Model is unavailable
+	Models []string
+
+	Temperature *float64
+	TopP        *float64
+	Seed        *int64
+	MaxTokens   *int
+
+	// ReasoningEffort asks reasoning models to think more or less before answering
+	ReasoningEffort openrouter.EffortEnum
+
+	// ProviderOrder lists the providers to try first, by name
+	ProviderOrder []string
+	// ProviderIgnore lists the providers never to use, by name
+	ProviderIgnore []string
+	// DataCollection controls whether providers that store prompts may be used
+	DataCollection openrouter.DataCollectionEnum
+}
+
+// site returns the site the quotes are for
+func (o Options) site() sites.Site {
+	if o.Site == (sites.Site{}) {
+		return sites.Frinkiac
+	}
+	return o.Site
+}
+
+// apply sets the options on a chat completion request
+func (o Options) apply(request *openrouter.ChatCompletionRequest) {
+	if o.Model != "" {
+		request.Model = o.Model
+	}
+
+	request.Models = o.Models
+	request.Temperature = o.Temperature
+	request.TopP = o.TopP
+	request.Seed = o.Seed
+	request.MaxTokens = o.MaxTokens
+
+	if o.ReasoningEffort != "" {
+		request.Reasoning = &openrouter.ReasoningRequest{Effort: o.ReasoningEffort}
+	}
+
+	if request.Provider == nil {
+		request.Provider = &openrouter.ProviderRequest{}
+	}
+	request.Provider.Order = o.ProviderOrder
+	request.Provider.Ignore = o.ProviderIgnore
+	request.Provider.DataCollection = o.DataCollection
+}
@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"testing"
+
+	openrouter "github.com/kklipsch/billy-bot/pkg/openrouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNewQuotesRequestOptions tests that the options are threaded into the quotes request
+func TestNewQuotesRequestOptions(t *testing.T) {
+	temperature := 0.2
+	seed := int64(42)
+
+	request := newQuotesRequest("classy", Options{
+		Model:           "openai/gpt-4o-mini",
+		Models:          []string{"anthropic/claude-3.5-haiku"},
+		Temperature:     &temperature,
+		Seed:            &seed,
+		ReasoningEffort: openrouter.EffortEnumLow,
+		ProviderIgnore:  []string{"DeepInfra"},
+		DataCollection:  openrouter.DenyDataCollection,
+	})
+
+	assert.Equal(t, "openai/gpt-4o-mini", request.Model)
+	assert.Equal(t, []string{"anthropic/claude-3.5-haiku"}, request.Models)
+	assert.Equal(t, &temperature, request.Temperature)
+	assert.Equal(t, &seed, request.Seed)
+	assert.Nil(t, request.TopP, "Unset options should be left to the model defaults")
+
+	require.NotNil(t, request.Reasoning)
+	assert.Equal(t, openrouter.EffortEnumLow, request.Reasoning.Effort)
+
+	require.NotNil(t, request.Provider)
+	assert.True(t, request.Provider.RequireParameters, "Quotes need providers that honor the response format")
+	assert.Equal(t, []string{"DeepInfra"}, request.Provider.Ignore)
+	assert.Equal(t, openrouter.DenyDataCollection, request.Provider.DataCollection)
+
+	assert.Empty(t, newQuotesRequest("classy", Options{}).Model, "The client's model should be used when none is set")
+}
@@ -2,59 +2,100 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
 
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
 	"github.com/kklipsch/billy-bot/pkg/jsonschema"
 	openrouter "github.com/kklipsch/billy-bot/pkg/openrouter"
 )
 
+// quotesRepairAttempts is how many times the model is asked to fix quotes that do not match the schema
+const quotesRepairAttempts = 2
+
 var (
 	// quotesResponseSchema defines the JSON schema for validating quote responses from the AI
-	quotesResponseSchema = jsonschema.NewArraySchema(
-		jsonschema.NewObjectSchema(
-			map[string]*jsonschema.Schema{
-				"quote":      jsonschema.NewStringSchema(),
-				"confidence": jsonschema.NewNumberSchema(),
-				"character":  jsonschema.NewStringSchema(),
-				"season":     jsonschema.NewIntegerSchema(),
-				"episode":    jsonschema.NewIntegerSchema(),
-			},
-			[]string{"quote", "confidence"},
-		),
-	)
+	quotesResponseSchema = jsonschema.Reflect[[]QuoteResponse]()
+)
 
-	// quotesPrompt is the system prompt used to instruct the AI model about Simpsons quotes
-	quotesPrompt = openrouter.ChatMessage{
+// quotesPrompt is the system prompt used to instruct the AI model about quotes from the site's show
+func quotesPrompt(site sites.Site) openrouter.ChatMessage {
+	return openrouter.ChatMessage{
 		Role: "system",
-		Content: `You are a helpful assistant with encyclopedic knowledge of The Simpsons. 
-		You have access to a website called frinkiac that can find scenes from the Simpsons based on the text used in closed captioning of the Simpsons.
-		Your goal is to categorize a set of text and think of any Simpsons quotes that are relevant to the text that should be findable in frinkiac.
-		Your output should be a list JSON quote objects with a confidence score from 0 to 1.0 and a quote that is a good search term for the frinkiac tool.
+		Content: fmt.Sprintf(`You are a helpful assistant with encyclopedic knowledge of %[1]s.
+		You have access to a website called %[2]s that can find scenes from %[1]s based on the text used in closed captioning of %[1]s.
+		Your goal is to categorize a set of text and think of any quotes from %[1]s that are relevant to the text that should be findable in %[2]s.
+		Your output should be a list JSON quote objects with a confidence score from 0 to 1.0 and a quote that is a good search term for the %[2]s tool.
 		If you can identify the season and episode number, include those as well.
-		You should sort the list by confidence score in descending order.`,
+		You should sort the list by confidence score in descending order.`, site.Show, site.Name),
 	}
-)
+}
 
 // QuoteResponse represents a quote response from the AI model
 type QuoteResponse struct {
-	Quote      string  `json:"quote"`
-	Confidence float64 `json:"confidence"`
-	Character  string  `json:"character,omitempty"`
-	Season     int     `json:"season,omitempty"`
-	Episode    int     `json:"episode,omitempty"`
+	Quote      string  `json:"quote" jsonschema:"description=A good search term for the quote in the closed captions."`
+	Confidence float64 `json:"confidence" jsonschema:"description=How relevant the quote is to the text,minimum=0,maximum=1"`
+	Character  string  `json:"character,omitempty" jsonschema:"description=The character who says the quote."`
+	Season     int     `json:"season,omitempty" jsonschema:"minimum=1"`
+	Episode    int     `json:"episode,omitempty" jsonschema:"minimum=1"`
+
+	// Show is the show the quote is from, it is set from the site the quotes were asked for rather than by the model
+	Show string `json:"-"`
+}
+
+// GetCandidateQuotes fetches candidate quotes from the show of opts.Site for a given prompt using OpenRouter AI
+func GetCandidateQuotes(ctx context.Context, client *openrouter.Client, prompt string, opts Options) ([]QuoteResponse, error) {
+	result := openrouter.Structured[[]QuoteResponse](ctx, client, newQuotesRequest(prompt, opts), openrouter.StructuredOptions{
+		Name:           "quotes",
+		Schema:         quotesResponseSchema,
+		RepairAttempts: quotesRepairAttempts,
+	})
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	if len(result.Result) == 0 {
+		return nil, fmt.Errorf("no quotes found in response")
+	}
+
+	return withShow(result.Result, opts.site()), nil
+}
+
+// StreamCandidateQuotes fetches candidate quotes like GetCandidateQuotes but streams the
+// model output to w as it is produced. The quotes are parsed once the stream completes.
+func StreamCandidateQuotes(ctx context.Context, client *openrouter.Client, prompt string, opts Options, w io.Writer) ([]QuoteResponse, error) {
+	events, err := client.StreamChatCompletion(ctx, newQuotesRequest(prompt, opts))
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := openrouter.CollectStream(events, func(chunk openrouter.ChatCompletionResponse) {
+		for _, choice := range chunk.Choices {
+			if choice.Index == 0 && choice.Delta != nil {
+				fmt.Fprint(w, choice.Delta.Content)
+			}
+		}
+	})
+	fmt.Fprintln(w)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in streamed response")
+	}
+
+	return parseQuotes(result.Choices[0].Message.Content, opts.site())
 }
 
-// GetCandidateQuotes fetches candidate Simpson quotes for a given prompt using OpenRouter AI
-func GetCandidateQuotes(ctx context.Context, prompt, apiKey string) ([]QuoteResponse, error) {
+// newQuotesRequest builds the chat completion request asking the model for quotes relevant to prompt
+func newQuotesRequest(prompt string, opts Options) openrouter.ChatCompletionRequest {
 	request := openrouter.ChatCompletionRequest{
-		Model: "openrouter/auto",
 		Messages: []openrouter.ChatMessage{
-			quotesPrompt,
+			quotesPrompt(opts.site()),
 			{Role: "user", Content: prompt},
 		},
-		ResponseFormatEnabled: openrouter.NewResponseFormatEnabled("quotes", quotesResponseSchema),
+		ResponseFormat: openrouter.NewResponseFormatEnabled("quotes", quotesResponseSchema),
 		BaseRequest: openrouter.BaseRequest{
 			Provider: &openrouter.ProviderRequest{
 				RequireParameters: true,
@@ -62,31 +103,28 @@ func GetCandidateQuotes(ctx context.Context, prompt, apiKey string) ([]QuoteResp
 		},
 	}
 
-	req, err := openrouter.NewChatCompletionReq(ctx, request)
-	result := openrouter.Call[openrouter.ChatCompletionResponse](ctx, apiKey, req, err, http.StatusOK)
-	if result.Err != nil {
-		return nil, result.Err
-	}
+	opts.apply(&request)
+	return request
+}
 
-	// Parse the response to extract quotes
-	var quotes []QuoteResponse
-	if err := json.Unmarshal([]byte(result.Body), &quotes); err != nil {
-		// Try to extract from the choices if direct unmarshaling fails
-		if len(result.Result.Choices) > 0 {
-			content := result.Result.Choices[0].Message.Content
-			if content != "" {
-				if err := json.Unmarshal([]byte(content), &quotes); err != nil {
-					return nil, fmt.Errorf("error parsing quotes from response content: %w", err)
-				}
-			}
-		} else {
-			return nil, fmt.Errorf("error parsing quotes from response: %w", err)
-		}
+// parseQuotes decodes and validates the quotes list from the content of a model message
+func parseQuotes(content string, site sites.Site) ([]QuoteResponse, error) {
+	quotes, err := openrouter.DecodeStructured[[]QuoteResponse](content, quotesResponseSchema)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing quotes from response content: %w", err)
 	}
 
 	if len(quotes) == 0 {
 		return nil, fmt.Errorf("no quotes found in response")
 	}
 
-	return quotes, nil
+	return withShow(quotes, site), nil
+}
+
+// withShow records the site's show on each quote
+func withShow(quotes []QuoteResponse, site sites.Site) []QuoteResponse {
+	for i := range quotes {
+		quotes[i].Show = site.Show
+	}
+	return quotes
 }
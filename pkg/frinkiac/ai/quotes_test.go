@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	openrouter "github.com/kklipsch/billy-bot/pkg/openrouter"
+	"github.com/kklipsch/billy-bot/pkg/openrouter/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGetCandidateQuotes tests the quotes request and parsing against the fake OpenRouter
+func TestGetCandidateQuotes(t *testing.T) {
+	server := fake.Start(fake.DefaultScript())
+	defer server.Close()
+
+	client := openrouter.NewClient("test-key")
+	client.BaseURL = server.URL
+
+	quotes, err := GetCandidateQuotes(context.Background(), client, "homer thinks he is smart", Options{})
+	require.NoError(t, err)
+	require.Len(t, quotes, 2)
+	assert.Equal(t, "I am so smart", quotes[0].Quote)
+	assert.Equal(t, 5, quotes[0].Season)
+	assert.Equal(t, "The Simpsons", quotes[0].Show, "The show should default to Frinkiac's")
+
+	var out bytes.Buffer
+	quotes, err = StreamCandidateQuotes(context.Background(), client, "homer thinks he is smart", Options{}, &out)
+	require.NoError(t, err)
+	require.Len(t, quotes, 2)
+	assert.Contains(t, out.String(), "Monorail", "The streamed content should be written as it arrives")
+}
+
+// TestGetCandidateQuotesSite tests that the prompt and quotes are for the show of the site
+func TestGetCandidateQuotesSite(t *testing.T) {
+	server := fake.NewServer(fake.DefaultScript())
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	client := openrouter.NewClient("test-key")
+	client.BaseURL = httpServer.URL
+
+	quotes, err := GetCandidateQuotes(context.Background(), client, "robots are better", Options{Site: sites.Morbotron})
+	require.NoError(t, err)
+	require.NotEmpty(t, quotes)
+	assert.Equal(t, "Futurama", quotes[0].Show)
+
+	requests := server.Requests()
+	require.Len(t, requests, 1)
+	prompt := requests[0].Messages[0].Content
+	assert.Contains(t, prompt, "knowledge of Futurama")
+	assert.Contains(t, prompt, "website called Morbotron")
+	assert.NotContains(t, prompt, "Simpsons")
+}
@@ -0,0 +1,103 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	openrouter "github.com/kklipsch/billy-bot/pkg/openrouter"
+)
+
+const (
+	// searchToolName is the name the model uses to call the frinkiac search tool
+	searchToolName = "frinkiac"
+
+	// searchToolResults is how many search results have their captions fetched for the model
+	searchToolResults = 3
+)
+
+// searchToolFunction describes the search tool for the site to the model
+func searchToolFunction(site sites.Site) openrouter.Function {
+	return openrouter.NewFunction[searchToolArgs](
+		searchToolName,
+		fmt.Sprintf("Search the closed captions of %s on %s and return the captions of the best matching scenes.", site.Show, site.Name),
+	)
+}
+
+// toolsPrompt tells the model it can check its quotes against the site before answering
+func toolsPrompt(site sites.Site) openrouter.ChatMessage {
+	return openrouter.ChatMessage{
+		Role: "system",
+		Content: fmt.Sprintf(`You can call the %s tool to search the real closed captions of %s on %s before answering.
+		Use it to check that your quotes exist and to correct their wording, season and episode.`, searchToolName, site.Show, site.Name),
+	}
+}
+
+// searchToolArgs are the arguments the model sends to the frinkiac search tool
+type searchToolArgs struct {
+	Quote string `json:"quote" jsonschema:"description=The quote or words to search the closed captions for."`
+}
+
+// searchToolScene is a single scene returned to the model by the frinkiac search tool
+type searchToolScene struct {
+	Episode   string `json:"episode"`
+	Timestamp string `json:"timestamp"`
+	Caption   string `json:"caption"`
+}
+
+// NewFrinkiacTools creates a tool runner that lets the model search the client's site for captions
+func NewFrinkiacTools(client *sites.Client) *openrouter.ToolRunner {
+	runner := openrouter.NewToolRunner()
+
+	openrouter.RegisterTool(runner, searchToolFunction(client.Site()), func(ctx context.Context, args searchToolArgs) (any, error) {
+		if args.Quote == "" {
+			return nil, fmt.Errorf("quote is required")
+		}
+
+		results, err := client.Search(ctx, args.Quote)
+		if err != nil {
+			return nil, err
+		}
+
+		scenes := []searchToolScene{}
+		for _, result := range results {
+			if len(scenes) == searchToolResults {
+				break
+			}
+
+			screenCap, err := client.Caption(ctx, result.EpisodID, result.Timestamp)
+			if err != nil {
+				continue
+			}
+
+			scenes = append(scenes, searchToolScene{
+				Episode:   string(result.EpisodID),
+				Timestamp: string(result.Timestamp),
+				Caption:   screenCap.Caption,
+			})
+		}
+
+		return scenes, nil
+	})
+
+	return runner
+}
+
+// GetCandidateQuotesWithTools fetches candidate quotes like GetCandidateQuotes but lets the model
+// call the tools in runner, e.g. to search frinkiac, before it answers. The runner's tools should
+// search the same site as opts.Site.
+func GetCandidateQuotesWithTools(ctx context.Context, client *openrouter.Client, runner *openrouter.ToolRunner, prompt string, opts Options) ([]QuoteResponse, error) {
+	request := newQuotesRequest(prompt, opts)
+	request.Messages = append([]openrouter.ChatMessage{request.Messages[0], toolsPrompt(opts.site())}, request.Messages[1:]...)
+
+	result, _ := runner.Run(ctx, client, request)
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	if len(result.Result.Choices) == 0 || result.Result.Choices[0].Message == nil {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	return parseQuotes(result.Result.Choices[0].Message.Content, opts.site())
+}
@@ -2,83 +2,359 @@ package frinkiac
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/kklipsch/billy-bot/pkg/config"
 	"github.com/kklipsch/billy-bot/pkg/frinkiac/ai"
-	"github.com/kklipsch/billy-bot/pkg/frinkiac/http"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	"github.com/kklipsch/billy-bot/pkg/openrouter"
+	"github.com/rs/zerolog/log"
 )
 
 // Command represents the CLI command group for Frinkiac
 type Command struct {
-	Complete CompleteCommand `cmd:"complete" help:"Find complete Simpsons scenes with quotes and screen captures."`
+	Complete CompleteCommand `cmd:"complete" help:"Find complete Simpsons, Futurama or Rick and Morty scenes with quotes and screen captures."`
 }
 
 // CompleteCommand represents the complete subcommand for finding Simpsons scenes
 type CompleteCommand struct {
-	Prompt string `arg:"" help:"The prompt to send to the AI model."`
-	Model  string `default:"openrouter/auto" help:"The model to use."`
-	APIKey string `name:"api-key" short:"k" help:"OpenRouter API key. If not provided, OPENROUTER_API_KEY env var is used."`
+	openrouter.ClientFlags `embed:""`
+	openrouter.UsageFlags  `embed:""`
+
+	Prompt         string        `arg:"" help:"The prompt to send to the AI model."`
+	Show           string        `enum:"simpsons,futurama,rickandmorty" default:"simpsons" help:"The show to find scenes from (simpsons on Frinkiac, futurama on Morbotron or rickandmorty on Master Of All Science)."`
+	Model          string        `default:"openrouter/auto" help:"The model to use."`
+	Stream         bool          `xor:"mode" help:"Stream the model output to stderr as it is generated."`
+	Tools          bool          `xor:"mode" help:"Let the model search Frinkiac to check its quotes before answering."`
+	SkipModelCheck bool          `name:"skip-model-check" help:"Don't check the model against the OpenRouter catalog before calling it."`
+	Candidates     int           `default:"5" help:"How many scenes per quote have their captions compared to the quote."`
+	SceneGap       time.Duration `name:"scene-gap" default:"2s" help:"Longest pause between Frinkiac frames of the same scene."`
+	Workers        int           `default:"4" help:"How many quotes to look up on Frinkiac at once."`
+	NoCache        bool          `name:"no-cache" help:"Don't use or update the cache of Frinkiac responses."`
+	CacheTTL       time.Duration `name:"cache-ttl" default:"168h" help:"How long a cached Frinkiac response is used before it is checked again."`
+	Rate           float64       `default:"4" help:"Most requests per second to send to Frinkiac, 0 for no limit."`
+	Burst          int           `default:"4" help:"Most requests to send to Frinkiac at once after a quiet period."`
+	MaxInFlight    int           `name:"max-in-flight" default:"2" help:"Most requests to have waiting on Frinkiac at once, 0 for no limit."`
+	UserAgent      string        `name:"user-agent" help:"User-Agent to send to Frinkiac. If not provided, billy-bot's own is used."`
+	Meme           bool          `help:"Print a meme of each best scene with its caption written on the frame."`
+	MemeText       string        `name:"meme-text" help:"Text to write on the memes instead of the caption, implies --meme."`
+	Clip           string        `enum:",gif,mp4" default:"" placeholder:"FORMAT" help:"Print a clip of the lines of each best scene (gif or mp4), with the meme text on it if --meme is set."`
+	FrinkiacURL    string        `name:"frinkiac-url" help:"Base URL of the show's site, e.g. a local billy-bot dev fake-frinkiac. If not provided, the site's env var (FRINKIAC_BASE_URL, MORBOTRON_BASE_URL or MASTEROFALLSCIENCE_BASE_URL) is used, then the real site."`
+
+	FallbackModels  []string `name:"fallback-model" help:"Models to try in order if the model is unavailable."`
+	Temperature     *float64 `help:"Sampling temperature."`
+	TopP            *float64 `name:"top-p" help:"Nucleus sampling probability mass."`
+	Seed            *int64   `help:"Seed for sampling, use with a fixed model to reproduce a run."`
+	MaxTokens       *int     `name:"max-tokens" help:"Maximum number of tokens to generate."`
+	ReasoningEffort string   `name:"reasoning-effort" enum:",low,medium,high" default:"" placeholder:"EFFORT" help:"Reasoning effort for reasoning models (low, medium or high)."`
+	ProviderOrder   []string `name:"provider-order" help:"Providers to try first, by name."`
+	ProviderIgnore  []string `name:"provider-ignore" help:"Providers never to use, by name."`
+	DataCollection  string   `name:"data-collection" enum:",allow,deny" default:"" placeholder:"POLICY" help:"Whether providers that store prompts may be used (allow or deny)."`
+
+	// Transport replaces the network for both OpenRouter and Frinkiac, e.g. with a cassette in tests
+	Transport http.RoundTripper `kong:"-"`
+	// Out is where the scenes are written, stdout if nil
+	Out io.Writer `kong:"-"`
 }
 
 // Run executes the complete command
 func (c *CompleteCommand) Run(ctx context.Context) error {
-	apiKey, err := config.GetFlagOrEnvVar(c.APIKey, "OPENROUTER_API_KEY")
+	orClient, err := c.NewClient()
 	if err != nil {
 		return err
 	}
 
-	quotes, err := ai.GetCandidateQuotes(ctx, c.Prompt, apiKey)
+	c.Track(orClient)
+	defer c.Report(ctx, orClient)
+
+	client, err := c.frinkiacClient()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		// the cache is only an optimisation, failing to save it doesn't fail the command
+		if err := client.Close(); err != nil {
+			log.Warn().Err(err).Msg("unable to save the frinkiac cache")
+		}
+	}()
 
-	// Create a Frinkiac HTTP client and config
-	client := http.NewHTTPClient()
-	config := http.DefaultConfig()
+	if c.Transport != nil {
+		orClient.HTTPClient = &http.Client{Transport: c.Transport}
+	}
 
-	// Process each quote
-	fmt.Println("Quotes found:")
-	for i, quote := range quotes {
-		fmt.Printf("%d. %s (confidence: %.2f) [S%02d E%02d]\n", i+1, quote.Quote, quote.Confidence, quote.Season, quote.Episode)
+	out := c.Out
+	if out == nil {
+		out = os.Stdout
+	}
 
-		// Search for the quote
-		results, err := http.GetQuote(ctx, client, config, quote.Quote)
-		if err != nil {
-			fmt.Printf("   Error searching for quote: %v\n", err)
-			continue
+	if !c.SkipModelCheck {
+		if err := c.checkModel(ctx, orClient); err != nil {
+			return err
 		}
+	}
 
-		if len(results) > 0 {
-			// Use the first result
-			result := results[0]
+	opts := c.options()
+	opts.Site = client.Site()
 
-			season, episode, err := http.GetSeasonAndEpisode(result.EpisodID)
-			if err != nil {
-				fmt.Printf("   Error parsing season/episode: %v\n", err)
-				continue
-			}
+	var quotes []ai.QuoteResponse
+	switch {
+	case c.Stream:
+		quotes, err = ai.StreamCandidateQuotes(ctx, orClient, c.Prompt, opts, os.Stderr)
+	case c.Tools:
+		quotes, err = ai.GetCandidateQuotesWithTools(ctx, orClient, ai.NewFrinkiacTools(client), c.Prompt, opts)
+	default:
+		quotes, err = ai.GetCandidateQuotes(ctx, orClient, c.Prompt, opts)
+	}
+	if err != nil {
+		return describeOpenRouterError(err)
+	}
 
-			seasonStr := fmt.Sprintf("S%02d", season)
-			episodeStr := fmt.Sprintf("E%02d", episode)
+	pipeline := Pipeline{
+		Matcher: Matcher{Client: client, Candidates: c.Candidates, SceneGap: c.SceneGap},
+		Workers: c.Workers,
+	}
+	resolutions := pipeline.Resolve(ctx, quotes)
 
-			fmt.Printf("   Found screen cap: Season %s, Episode %s, ID %s\n", seasonStr, episodeStr, result.Timestamp)
+	fmt.Fprintln(out, "Quotes found:")
+	var errs []error
+	for i, resolution := range resolutions {
+		quote := resolution.Quote
+		fmt.Fprintf(out, "%d. %s (confidence: %.2f) [S%02d E%02d]\n", i+1, quote.Quote, quote.Confidence, quote.Season, quote.Episode)
 
-			// Get the screen cap
-			screenCap, err := http.GetScreenCap(ctx, client, config, season, episode, result.Timestamp)
-			if err != nil {
-				fmt.Printf("   Error getting screen cap: %v\n", err)
-				continue
+		best, ok := resolution.Best()
+		switch {
+		case resolution.Err != nil:
+			fmt.Fprintln(out, "   Unable to find screen caps for this quote")
+			errs = append(errs, fmt.Errorf("quote %d %q: %w", i+1, quote.Quote, resolution.Err))
+		case !ok:
+			fmt.Fprintln(out, "   No screen caps found for this quote")
+		default:
+			fmt.Fprintf(out, "   Found screen cap: Season S%02d, Episode E%02d, ID %s (match: %.2f)\n", best.Season, best.Episode, best.Result.Timestamp, best.Similarity)
+			fmt.Fprintf(out, "   Scene: %s\n", best.Scene)
+			fmt.Fprintf(out, "   Caption: %s\n", best.ScreenCap.Caption)
+			fmt.Fprintf(out, "   Image URL: %s%s\n", client.BaseURL(), best.ScreenCap.ImagePath)
+			if c.Meme || c.MemeText != "" {
+				c.printMeme(out, client, quote, best)
+			}
+			if c.Clip != "" {
+				c.printClip(out, client, quote, best)
 			}
+		}
+
+		fmt.Fprintln(out)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		fmt.Fprintln(out, "Errors:")
+		for _, err := range errs {
+			fmt.Fprintf(out, "   %v\n", err)
+		}
+
+		if len(errs) == len(resolutions) {
+			return fmt.Errorf("unable to find screen caps for any quote: %w", errors.Join(errs...))
+		}
+	}
+
+	return nil
+}
+
+// frinkiacClient returns the client for the site of --show, pointed at --frinkiac-url or the site's own
+// env var if set and caching responses unless --no-cache is set. Each site has its own env var so
+// FRINKIAC_BASE_URL doesn't send other shows' quotes to Frinkiac.
+func (c *CompleteCommand) frinkiacClient() (*sites.Client, error) {
+	site := sites.Frinkiac
+	if c.Show != "" {
+		var err error
+		if site, err = sites.SiteForShow(c.Show); err != nil {
+			return nil, err
+		}
+	}
+
+	opts := []sites.Option{
+		sites.WithSite(site),
+		sites.WithLimiter(sites.NewLimiter(c.Rate, c.Burst, c.MaxInFlight)),
+	}
+	if c.UserAgent != "" {
+		opts = append(opts, sites.WithUserAgent(c.UserAgent))
+	}
+	if c.Transport != nil {
+		opts = append(opts, sites.WithTransport(c.Transport))
+	}
+
+	if baseURL, err := config.GetFlagOrEnvVar(c.FrinkiacURL, site.BaseURLEnv); err == nil {
+		opts = append(opts, sites.WithBaseURL(baseURL))
+	}
+
+	if !c.NoCache {
+		cache := sites.DefaultCache()
+		if c.CacheTTL > 0 {
+			cache.TTL = c.CacheTTL
+		}
+		opts = append(opts, sites.WithCache(cache))
+	}
+
+	return sites.NewClient(opts...), nil
+}
+
+// memeText returns the text to write on the match, --meme-text, the caption or failing that the quote
+func (c *CompleteCommand) memeText(quote ai.QuoteResponse, match Match) string {
+	switch {
+	case c.MemeText != "":
+		return c.MemeText
+	case match.ScreenCap.Caption != "":
+		return match.ScreenCap.Caption
+	default:
+		return quote.Quote
+	}
+}
+
+// printMeme writes the URL of a meme of the match
+func (c *CompleteCommand) printMeme(out io.Writer, client *sites.Client, quote ai.QuoteResponse, match Match) {
+	path, err := sites.GetMemePath(match.Result.EpisodID, match.Result.Timestamp, c.memeText(quote, match))
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to build meme")
+		return
+	}
 
-			fmt.Printf("   Caption: %s\n", screenCap.Caption)
-			fmt.Printf("   Image URL: %s%s\n", http.BaseURL, screenCap.ImagePath)
-		} else {
-			fmt.Println("   No screen caps found for this quote")
+	fmt.Fprintf(out, "   Meme URL: %s%s\n", client.BaseURL(), path)
+}
+
+// printClip writes the URL of a clip of the lines of the match, with the meme text on it if memes are wanted
+func (c *CompleteCommand) printClip(out io.Writer, client *sites.Client, quote ai.QuoteResponse, match Match) {
+	clip, err := sites.ClipRange(match.ScreenCap, sites.MaxClipDuration)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to find the clip of the scene")
+		return
+	}
+
+	text := ""
+	if c.Meme || c.MemeText != "" {
+		text = c.memeText(quote, match)
+	}
+
+	path, err := sites.GetClipPath(clip, sites.ClipFormat(c.Clip), text)
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to build clip")
+		return
+	}
+
+	fmt.Fprintf(out, "   Clip URL: %s%s (%s)\n", client.BaseURL(), path, clip.Duration())
+}
+
+// options collects the generation flags into the options for the ai package
+func (c *CompleteCommand) options() ai.Options {
+	return ai.Options{
+		Model:           c.Model,
+		Models:          c.FallbackModels,
+		Temperature:     c.Temperature,
+		TopP:            c.TopP,
+		Seed:            c.Seed,
+		MaxTokens:       c.MaxTokens,
+		ReasoningEffort: openrouter.EffortEnum(c.ReasoningEffort),
+		ProviderOrder:   c.ProviderOrder,
+		ProviderIgnore:  c.ProviderIgnore,
+		DataCollection:  openrouter.DataCollectionEnum(c.DataCollection),
+	}
+}
+
+// checkModel makes sure the model exists and warns if it lacks the parameters the command relies on.
+// If the catalog can't be fetched the check is skipped rather than failing the command.
+func (c *CompleteCommand) checkModel(ctx context.Context, client *openrouter.Client) error {
+	models, err := client.CachedModels(ctx, openrouter.DefaultModelCache())
+	if err != nil {
+		log.Warn().Err(err).Msg("unable to fetch the model catalog, skipping the model check")
+		return nil
+	}
+
+	required := []string{"structured_outputs"}
+	if c.Tools {
+		required = append(required, "tools")
+	}
+
+	missing, err := openrouter.ValidateModel(models, c.Model, required...)
+	if err != nil {
+		return err
+	}
+
+	for _, param := range missing {
+		log.Warn().Str("model", c.Model).Str("parameter", param).Msg("model does not list support for a parameter this command uses, results may be unreliable")
+	}
+
+	for _, fallback := range c.FallbackModels {
+		if _, err := openrouter.ValidateModel(models, fallback); err != nil {
+			return fmt.Errorf("invalid fallback model: %w", err)
 		}
+	}
+
+	return nil
+}
+
+// describeOpenRouterError adds guidance to the OpenRouter failures a user can act on
+func describeOpenRouterError(err error) error {
+	switch {
+	case errors.Is(err, openrouter.ErrBudgetExceeded):
+		return fmt.Errorf("the OpenRouter budget has been reached, raise --daily-budget or --monthly-budget to continue: %w", err)
+	case openrouter.IsInsufficientCredits(err):
+		return fmt.Errorf("the OpenRouter account is out of credits, add more at https://openrouter.ai/settings/credits: %w", err)
+	case openrouter.IsRateLimited(err):
+		return fmt.Errorf("OpenRouter is rate limiting requests, try again later or with another model: %w", err)
+	case openrouter.IsContextLengthExceeded(err):
+		return fmt.Errorf("the prompt is too long for the model, shorten it or pick a model with a larger context: %w", err)
+	case openrouter.IsModerated(err):
+		return fmt.Errorf("the prompt was rejected by the model's moderation: %w", err)
+	default:
+		return err
+	}
+}
+
+// CacheCommand represents the CLI command group for the cache of Frinkiac responses
+type CacheCommand struct {
+	Stats CacheStatsCommand `cmd:"stats" help:"Show what is in the Frinkiac cache."`
+	Clear CacheClearCommand `cmd:"clear" help:"Remove every cached Frinkiac response."`
+}
 
-		fmt.Println()
+// CacheStatsCommand represents the stats subcommand for describing the cache
+type CacheStatsCommand struct{}
+
+// Run executes the stats command
+func (c *CacheStatsCommand) Run() error {
+	stats, err := sites.DefaultCache().Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Path:    %s\n", stats.Path)
+	fmt.Printf("Entries: %d\n", stats.Entries)
+	fmt.Printf("Size:    %.1f KiB\n", float64(stats.Bytes)/1024)
+	fmt.Printf("Hits:    %d\n", stats.Hits)
+	fmt.Printf("Misses:  %d\n", stats.Misses)
+	if stats.Entries > 0 {
+		fmt.Printf("Oldest:  %s\n", stats.Oldest.Local().Format(time.DateTime))
+		fmt.Printf("Newest:  %s\n", stats.Newest.Local().Format(time.DateTime))
+	}
+
+	return nil
+}
+
+// CacheClearCommand represents the clear subcommand for emptying the cache
+type CacheClearCommand struct{}
+
+// Run executes the clear command
+func (c *CacheClearCommand) Run() error {
+	cache := sites.DefaultCache()
+	if err := cache.Clear(); err != nil {
+		return err
 	}
 
+	fmt.Printf("Cleared %s\n", cache.Path)
 	return nil
 }
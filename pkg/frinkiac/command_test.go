@@ -0,0 +1,108 @@
+package frinkiac
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/cassette"
+	"github.com/kklipsch/billy-bot/pkg/openrouter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompleteCommand runs the whole pipeline against canned OpenRouter and Frinkiac responses.
+// testdata/complete_smart.json is a hand written fixture in the cassette format, not a recording,
+// so it is only ever replayed.
+func TestCompleteCommand(t *testing.T) {
+	// the developer's environment must not redirect requests or turn on the budget's key check
+	for _, key := range []string{
+		"FRINKIAC_BASE_URL", "MORBOTRON_BASE_URL", "MASTEROFALLSCIENCE_BASE_URL", "OPENROUTER_BASE_URL",
+		"BILLY_BOT_DAILY_BUDGET", "BILLY_BOT_MONTHLY_BUDGET", "BILLY_BOT_BUDGET_MODEL",
+	} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("OPENROUTER_API_KEY", "test-key")
+
+	transport, err := cassette.New("testdata/complete_smart.json", cassette.ModeReplay)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	cmd := CompleteCommand{
+		ClientFlags:    openrouter.ClientFlags{MaxAttempts: 1},
+		UsageFlags:     openrouter.UsageFlags{Usage: "none"},
+		Prompt:         "I finally understand the tax code",
+		Model:          "openai/gpt-4o-mini",
+		SkipModelCheck: true,
+		NoCache:        true,
+		Meme:           true,
+		Clip:           "gif",
+		Transport:      transport,
+		Out:            &out,
+	}
+
+	require.NoError(t, cmd.Run(context.Background()))
+
+	assert.Equal(t, `Quotes found:
+1. I am so smart (confidence: 0.92) [S05 E03]
+   Found screen cap: Season S05, Episode E03, ID 1074040 (match: 1.00)
+   Scene: 17:54.0-17:55.2 (2 frames)
+   Caption: I am so smart! I am so smart! S-M-R-T! I mean S-M-A-R-T!
+   Image URL: https://frinkiac.com/img/S05E03/1074040/medium.jpg
+   Meme URL: https://frinkiac.com/meme/S05E03/1074040.jpg?b64lines=SSBhbSBzbyBzbWFydCEgSSBhbSBzbwpzbWFydCEgUy1NLVItVCEgSSBtZWFuClMtTS1BLVItVCE%3D
+   Clip URL: https://frinkiac.com/gif/S05E03/1072820/1077720.gif?b64lines=SSBhbSBzbyBzbWFydCEgSSBhbSBzbwpzbWFydCEgUy1NLVItVCEgSSBtZWFuClMtTS1BLVItVCE%3D (4.9s)
+
+2. the tax code is a garden of delights (confidence: 0.31) [S00 E00]
+   No screen caps found for this quote
+
+`, out.String())
+}
+
+// TestFrinkiacClientBaseURL tests that each show's site is only overridden by its own env var
+func TestFrinkiacClientBaseURL(t *testing.T) {
+	t.Setenv("FRINKIAC_BASE_URL", "http://localhost:8080")
+	t.Setenv("MORBOTRON_BASE_URL", "")
+
+	simpsons, err := (&CompleteCommand{Show: "simpsons", NoCache: true}).frinkiacClient()
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080", simpsons.BaseURL())
+
+	futurama, err := (&CompleteCommand{Show: "futurama", NoCache: true}).frinkiacClient()
+	require.NoError(t, err)
+	assert.Equal(t, "https://morbotron.com", futurama.BaseURL(), "FRINKIAC_BASE_URL should not redirect other shows")
+
+	t.Setenv("MORBOTRON_BASE_URL", "http://localhost:8081")
+	futurama, err = (&CompleteCommand{Show: "futurama", NoCache: true}).frinkiacClient()
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8081", futurama.BaseURL())
+
+	flagged, err := (&CompleteCommand{Show: "rickandmorty", NoCache: true, FrinkiacURL: "http://localhost:9090"}).frinkiacClient()
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:9090", flagged.BaseURL(), "The flag should apply to any show")
+}
+
+// TestFrinkiacClientFlags tests that the politeness flags configure the Frinkiac client
+func TestFrinkiacClientFlags(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+		fmt.Fprint(w, `[]`)
+	}))
+	defer server.Close()
+
+	cmd := &CompleteCommand{NoCache: true, FrinkiacURL: server.URL, Rate: 1, Burst: 1, MaxInFlight: 1, UserAgent: "test-agent/1.0"}
+	client, err := cmd.frinkiacClient()
+	require.NoError(t, err)
+
+	start := time.Now()
+	for range 2 {
+		_, err := client.Search(context.Background(), "I am so smart")
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond, "The second request should wait for the rate limit")
+	assert.Equal(t, []string{"test-agent/1.0", "test-agent/1.0"}, userAgents)
+}
@@ -0,0 +1,483 @@
+// Package fake serves a small Frinkiac lookalike for tests and offline development.
+// It implements the search, caption, random and episode API endpoints, the HTML caption page, images,
+// memes and clips from a fixture corpus, and can inject latency, errors and malformed JSON.
+package fake
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed corpus.json
+var corpusJSON []byte
+
+// maxClipDuration is the longest GIF or MP4 Frinkiac renders
+const maxClipDuration = 10 * time.Second
+
+// Corpus is the set of episodes the fake serves
+type Corpus struct {
+	Episodes []Episode `json:"episodes"`
+}
+
+// Episode is an episode in the corpus
+type Episode struct {
+	Key             string     `json:"key"` // e.g. S05E03
+	Title           string     `json:"title"`
+	Director        string     `json:"director"`
+	Writer          string     `json:"writer"`
+	OriginalAirDate string     `json:"original_air_date"`
+	Subtitles       []Subtitle `json:"subtitles"`
+}
+
+// Subtitle is a line of closed captions, timestamps are milliseconds into the episode
+type Subtitle struct {
+	ID      int    `json:"id"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Content string `json:"content"`
+}
+
+// DefaultCorpus returns the built in corpus of a few well known scenes
+func DefaultCorpus() Corpus {
+	var corpus Corpus
+	if err := json.Unmarshal(corpusJSON, &corpus); err != nil {
+		panic(fmt.Sprintf("invalid embedded frinkiac corpus: %v", err))
+	}
+	return corpus
+}
+
+// Config controls the faults the fake injects
+type Config struct {
+	// Latency is added to every response
+	Latency time.Duration
+	// ErrorRate is the fraction of API responses, 0 to 1, that fail with a 500
+	ErrorRate float64
+	// MalformedRate is the fraction of API responses, 0 to 1, whose JSON is cut short
+	MalformedRate float64
+	// Seed makes the injected faults repeatable
+	Seed uint64
+}
+
+// frame is a screen cap of an episode, each subtitle has one at its start and its midpoint
+type frame struct {
+	ID        int
+	Episode   string
+	Timestamp int
+}
+
+// Server is an http.Handler that behaves like Frinkiac.
+// Errors and malformed JSON are only injected into the /api/ endpoints so the HTML and image
+// endpoints remain available, which is what Client.Caption falls back to.
+type Server struct {
+	config   Config
+	episodes map[string]Episode
+	frames   []frame
+	mux      *http.ServeMux
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewServer creates a Server for corpus
+func NewServer(corpus Corpus, config Config) *Server {
+	s := &Server{
+		config:   config,
+		episodes: map[string]Episode{},
+		mux:      http.NewServeMux(),
+		rand:     rand.New(rand.NewPCG(config.Seed, config.Seed)),
+	}
+
+	id := 1
+	for _, episode := range corpus.Episodes {
+		s.episodes[episode.Key] = episode
+		for _, subtitle := range episode.Subtitles {
+			for _, ts := range []int{subtitle.Start, (subtitle.Start + subtitle.End) / 2} {
+				s.frames = append(s.frames, frame{ID: id, Episode: episode.Key, Timestamp: ts})
+				id++
+			}
+		}
+	}
+
+	s.mux.HandleFunc("GET /api/search", s.api(s.search))
+	s.mux.HandleFunc("GET /api/caption", s.api(s.caption))
+	s.mux.HandleFunc("GET /api/random", s.api(s.random))
+	s.mux.HandleFunc("GET /api/episode/{episode}/{start}/{end}", s.api(s.episode))
+	s.mux.HandleFunc("GET /caption/{episode}/{timestamp}", s.captionPage)
+	s.mux.HandleFunc("GET /img/{episode}/{file...}", s.image)
+	s.mux.HandleFunc("GET /meme/{episode}/{file}", s.meme)
+	s.mux.HandleFunc("GET /gif/{episode}/{start}/{file}", s.clip("gif"))
+	s.mux.HandleFunc("GET /mp4/{episode}/{start}/{file}", s.clip("mp4"))
+
+	return s
+}
+
+// Start serves the default corpus on a local port, close the returned server when done
+func Start(config Config) *httptest.Server {
+	return httptest.NewServer(NewServer(DefaultCorpus(), config))
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Str("method", r.Method).Str("url", r.URL.String()).Msg("fake frinkiac request")
+
+	if s.config.Latency > 0 {
+		select {
+		case <-time.After(s.config.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// api wraps a JSON endpoint with the injected errors and malformed responses
+func (s *Server) api(handler func(*http.Request) (any, int)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.roll(s.config.ErrorRate) {
+			http.Error(w, "injected error", http.StatusInternalServerError)
+			return
+		}
+
+		body, status := handler(r)
+		data, err := json.Marshal(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if s.roll(s.config.MalformedRate) {
+			data = data[:len(data)/2]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(data)
+	}
+}
+
+// roll reports whether a fault with the given rate should happen
+func (s *Server) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rand.Float64() < rate
+}
+
+// searchResult matches the JSON of a Frinkiac search result
+type searchResult struct {
+	ID        int    `json:"Id"`
+	Episode   string `json:"Episode"`
+	Timestamp int    `json:"Timestamp"`
+}
+
+// search returns the frames of every subtitle containing the query
+func (s *Server) search(r *http.Request) (any, int) {
+	query := normalize(r.URL.Query().Get("q"))
+
+	results := []searchResult{}
+	if query == "" {
+		return results, http.StatusOK
+	}
+
+	for _, f := range s.frames {
+		for _, subtitle := range s.subtitlesAt(f) {
+			if strings.Contains(normalize(subtitle.Content), query) {
+				results = append(results, searchResult{ID: f.ID, Episode: f.Episode, Timestamp: f.Timestamp})
+				break
+			}
+		}
+	}
+
+	return results, http.StatusOK
+}
+
+// caption returns the episode, frame, subtitles and nearby frames for e and t
+func (s *Server) caption(r *http.Request) (any, int) {
+	episode := r.URL.Query().Get("e")
+	timestamp, err := strconv.Atoi(r.URL.Query().Get("t"))
+	if err != nil {
+		return map[string]string{"error": "invalid timestamp"}, http.StatusBadRequest
+	}
+
+	i := slices.IndexFunc(s.frames, func(f frame) bool { return f.Episode == episode && f.Timestamp == timestamp })
+	if i < 0 {
+		return map[string]string{"error": "frame not found"}, http.StatusNotFound
+	}
+
+	return s.captionFor(i), http.StatusOK
+}
+
+// random returns the caption of a random frame
+func (s *Server) random(*http.Request) (any, int) {
+	s.mu.Lock()
+	i := s.rand.IntN(len(s.frames))
+	s.mu.Unlock()
+
+	return s.captionFor(i), http.StatusOK
+}
+
+// episode returns the frames of an episode between start and end inclusive
+func (s *Server) episode(r *http.Request) (any, int) {
+	start, startErr := strconv.Atoi(r.PathValue("start"))
+	end, endErr := strconv.Atoi(r.PathValue("end"))
+	if startErr != nil || endErr != nil {
+		return map[string]string{"error": "invalid timestamp"}, http.StatusBadRequest
+	}
+
+	episode := r.PathValue("episode")
+	if _, ok := s.episodes[episode]; !ok {
+		return map[string]string{"error": "episode not found"}, http.StatusNotFound
+	}
+
+	results := []searchResult{}
+	for _, f := range s.frames {
+		if f.Episode == episode && f.Timestamp >= start && f.Timestamp <= end {
+			results = append(results, searchResult{ID: f.ID, Episode: f.Episode, Timestamp: f.Timestamp})
+		}
+	}
+
+	// frames are built a subtitle at a time so sort them into the order they are shown
+	slices.SortFunc(results, func(a, b searchResult) int { return a.Timestamp - b.Timestamp })
+
+	return results, http.StatusOK
+}
+
+// captionFor builds the caption response for the frame at index i, shaped like sites.APICaption
+func (s *Server) captionFor(i int) any {
+	f := s.frames[i]
+	episode := s.episodes[f.Episode]
+
+	season, number := 0, 0
+	fmt.Sscanf(episode.Key, "S%02dE%02d", &season, &number)
+
+	type subtitle struct {
+		ID                      int    `json:"Id"`
+		RepresentativeTimestamp int    `json:"RepresentativeTimestamp"`
+		Episode                 string `json:"Episode"`
+		StartTimestamp          int    `json:"StartTimestamp"`
+		EndTimestamp            int    `json:"EndTimestamp"`
+		Content                 string `json:"Content"`
+		Language                string `json:"Language"`
+	}
+
+	subtitles := []subtitle{}
+	for _, sub := range s.subtitlesAt(f) {
+		subtitles = append(subtitles, subtitle{
+			ID:                      sub.ID,
+			RepresentativeTimestamp: (sub.Start + sub.End) / 2,
+			Episode:                 episode.Key,
+			StartTimestamp:          sub.Start,
+			EndTimestamp:            sub.End,
+			Content:                 sub.Content,
+			Language:                "en",
+		})
+	}
+
+	nearby := []searchResult{}
+	for j := max(0, i-2); j <= min(len(s.frames)-1, i+2); j++ {
+		if s.frames[j].Episode == f.Episode {
+			nearby = append(nearby, searchResult{ID: s.frames[j].ID, Episode: f.Episode, Timestamp: s.frames[j].Timestamp})
+		}
+	}
+
+	return map[string]any{
+		"Episode": map[string]any{
+			"Id":              season*100 + number,
+			"Key":             episode.Key,
+			"Season":          season,
+			"EpisodeNumber":   number,
+			"Title":           episode.Title,
+			"Director":        episode.Director,
+			"Writer":          episode.Writer,
+			"OriginalAirDate": episode.OriginalAirDate,
+			"WikiLink":        "https://en.wikipedia.org/wiki/" + strings.ReplaceAll(episode.Title, " ", "_"),
+		},
+		"Frame":     searchResult{ID: f.ID, Episode: f.Episode, Timestamp: f.Timestamp},
+		"Subtitles": subtitles,
+		"Nearby":    nearby,
+	}
+}
+
+// subtitlesAt returns the subtitles on screen at a frame
+func (s *Server) subtitlesAt(f frame) []Subtitle {
+	var subtitles []Subtitle
+	for _, subtitle := range s.episodes[f.Episode].Subtitles {
+		if subtitle.Start <= f.Timestamp && f.Timestamp < subtitle.End {
+			subtitles = append(subtitles, subtitle)
+		}
+	}
+	return subtitles
+}
+
+// captionTemplate is a cut down version of the Frinkiac caption page
+var captionTemplate = template.Must(template.New("caption").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<title>Frinkiac - {{.Title}}</title>
+<meta property="og:image" content="/img/{{.Episode}}/{{.Timestamp}}.jpg">
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<img src="/img/{{.Episode}}/{{.Timestamp}}/medium.jpg">
+{{range .Lines}}<p>{{.}}</p>
+{{end}}</body>
+</html>
+`))
+
+// captionPage serves the HTML caption page, which is never faulted
+func (s *Server) captionPage(w http.ResponseWriter, r *http.Request) {
+	episode := r.PathValue("episode")
+	timestamp, err := strconv.Atoi(r.PathValue("timestamp"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	i := slices.IndexFunc(s.frames, func(f frame) bool { return f.Episode == episode && f.Timestamp == timestamp })
+	if i < 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var lines []string
+	for _, subtitle := range s.subtitlesAt(s.frames[i]) {
+		lines = append(lines, subtitle.Content)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	captionTemplate.Execute(w, map[string]any{
+		"Title":     s.episodes[episode].Title,
+		"Episode":   episode,
+		"Timestamp": timestamp,
+		"Lines":     lines,
+	})
+}
+
+// image serves a small solid JPEG for /img/{episode}/{timestamp}.jpg and /img/{episode}/{timestamp}/{size}.jpg
+func (s *Server) image(w http.ResponseWriter, r *http.Request) {
+	episode := r.PathValue("episode")
+	file := strings.TrimSuffix(r.PathValue("file"), ".jpg")
+	timestamp, _, _ := strings.Cut(file, "/")
+
+	if _, ok := s.episodes[episode]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	// a colour per frame so different frames are distinguishable
+	n, _ := strconv.Atoi(timestamp)
+	img := image.NewRGBA(image.Rect(0, 0, 160, 120))
+	fill := color.RGBA{R: uint8(n), G: uint8(n >> 8), B: uint8(n >> 16), A: 255}
+	for y := range 120 {
+		for x := range 160 {
+			img.Set(x, y, fill)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(buf.Bytes())
+}
+
+// meme serves the frame's image for /meme/{episode}/{timestamp}.jpg, the text isn't drawn but
+// b64lines must be valid base64url like Frinkiac requires
+func (s *Server) meme(w http.ResponseWriter, r *http.Request) {
+	if _, err := base64.URLEncoding.DecodeString(r.URL.Query().Get("b64lines")); err != nil {
+		http.Error(w, "invalid b64lines", http.StatusBadRequest)
+		return
+	}
+
+	s.image(w, r)
+}
+
+// clip serves /{format}/{episode}/{start}/{end}.{format}, a two frame GIF of the start and end colours
+// or a stub MP4, rejecting clips longer than Frinkiac renders
+func (s *Server) clip(format string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, startErr := strconv.Atoi(r.PathValue("start"))
+		end, endErr := strconv.Atoi(strings.TrimSuffix(r.PathValue("file"), "."+format))
+		if startErr != nil || endErr != nil || end <= start || time.Duration(end-start)*time.Millisecond > maxClipDuration {
+			http.Error(w, "invalid clip", http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := s.episodes[r.PathValue("episode")]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		if _, err := base64.URLEncoding.DecodeString(r.URL.Query().Get("b64lines")); err != nil {
+			http.Error(w, "invalid b64lines", http.StatusBadRequest)
+			return
+		}
+
+		if format == "mp4" {
+			// just the file type box, enough for anything sniffing the content
+			w.Header().Set("Content-Type", "video/mp4")
+			w.Write([]byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom"))
+			return
+		}
+
+		anim := &gif.GIF{}
+		for _, n := range []int{start, end} {
+			fill := color.RGBA{R: uint8(n), G: uint8(n >> 8), B: uint8(n >> 16), A: 255}
+			frame := image.NewPaletted(image.Rect(0, 0, 160, 120), color.Palette{fill})
+			anim.Image = append(anim.Image, frame)
+			anim.Delay = append(anim.Delay, 50)
+		}
+
+		var buf bytes.Buffer
+		if err := gif.EncodeAll(&buf, anim); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write(buf.Bytes())
+	}
+}
+
+// normalize lowercases text and drops punctuation so searches match like Frinkiac's do
+func normalize(text string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+			space = false
+		case unicode.IsSpace(r) && !space && sb.Len() > 0:
+			sb.WriteRune(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,157 @@
+package fake_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/gif"
+	"net/http"
+	"testing"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/fake"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSearchAndCaption tests that the fake answers searches and captions like Frinkiac
+func TestSearchAndCaption(t *testing.T) {
+	server := fake.Start(fake.Config{})
+	defer server.Close()
+
+	client := sites.NewClient(sites.WithBaseURL(server.URL), sites.WithLimiter(nil))
+
+	results, err := client.Search(context.Background(), "i am so SMART")
+	require.NoError(t, err)
+	require.NotEmpty(t, results, "Searches should ignore case and punctuation")
+	assert.Equal(t, sites.EpisodeID("S05E03"), results[0].EpisodID)
+
+	screenCap, err := client.Caption(context.Background(), results[0].EpisodID, results[0].Timestamp)
+	require.NoError(t, err)
+	assert.Equal(t, "I am so smart! I am so smart!", screenCap.Caption)
+	assert.Equal(t, "/img/S05E03/"+string(results[0].Timestamp)+"/medium.jpg", screenCap.ImagePath)
+
+	image, err := client.Image(context.Background(), results[0].EpisodID, results[0].Timestamp)
+	require.NoError(t, err)
+	assert.NotEmpty(t, image, "The image should be served")
+
+	meme, err := client.Meme(context.Background(), results[0].EpisodID, results[0].Timestamp, screenCap.Caption)
+	require.NoError(t, err)
+	assert.Equal(t, image, meme, "The meme should be served")
+
+	results, err = client.Search(context.Background(), "cromulent")
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+// TestRandom tests that random returns a caption for a frame in the corpus
+func TestRandom(t *testing.T) {
+	server := fake.Start(fake.Config{Seed: 7})
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL + "/api/random")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var caption sites.APICaption
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&caption))
+	assert.NotEmpty(t, caption.Episode.Key)
+	assert.NotEmpty(t, caption.Subtitles)
+
+	screenCap, err := sites.NewClient(sites.WithBaseURL(server.URL)).Random(context.Background())
+	require.NoError(t, err)
+	assert.NotEmpty(t, screenCap.Caption)
+	assert.NotEmpty(t, screenCap.ImagePath)
+}
+
+// TestEpisode tests that the frames of an episode between two timestamps are returned in order
+func TestEpisode(t *testing.T) {
+	server := fake.Start(fake.Config{})
+	defer server.Close()
+
+	client := sites.NewClient(sites.WithBaseURL(server.URL))
+
+	results, err := client.Search(context.Background(), "I am so smart")
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+
+	frames, err := client.Episode(context.Background(), "S05E03", "0", "10000000")
+	require.NoError(t, err)
+	require.NotEmpty(t, frames)
+	assert.Contains(t, frames, results[0])
+	last := -1
+	for _, frame := range frames {
+		assert.Equal(t, sites.EpisodeID("S05E03"), frame.EpisodID)
+		ms, err := frame.Timestamp.Milliseconds()
+		require.NoError(t, err)
+		assert.Greater(t, ms, last, "Frames should be in order")
+		last = ms
+	}
+
+	frames, err = client.Episode(context.Background(), "S05E03", "0", "1")
+	require.NoError(t, err)
+	assert.Empty(t, frames)
+
+	_, err = client.Episode(context.Background(), "S5E3", "0", "1")
+	assert.Error(t, err, "The episode should be validated")
+}
+
+// TestClip tests that clips of a caption's lines are served as GIFs and MP4s
+func TestClip(t *testing.T) {
+	server := fake.Start(fake.Config{})
+	defer server.Close()
+
+	client := sites.NewClient(sites.WithBaseURL(server.URL), sites.WithLimiter(nil))
+
+	screenCap, err := client.Caption(context.Background(), "S07E21", "352500")
+	require.NoError(t, err)
+
+	clip, err := sites.ClipRange(screenCap, 0)
+	require.NoError(t, err)
+	assert.Equal(t, sites.EpisodeID("S07E21"), clip.Episode)
+	assert.LessOrEqual(t, clip.Start, 352500)
+	assert.GreaterOrEqual(t, clip.End, 352500, "The clip should show the frame")
+
+	data, err := client.Clip(context.Background(), clip, sites.ClipGIF, "Steamed hams")
+	require.NoError(t, err)
+	animation, err := gif.DecodeAll(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Len(t, animation.Image, 2)
+
+	data, err = client.Clip(context.Background(), clip, sites.ClipMP4, "")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "ftyp")
+
+	resp, err := server.Client().Get(server.URL + "/gif/S07E21/0/60000.gif")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Clips longer than Frinkiac renders should be rejected")
+}
+
+// TestFaults tests that API faults make Caption fall back to the HTML page
+func TestFaults(t *testing.T) {
+	for name, config := range map[string]fake.Config{
+		"Errors":    {ErrorRate: 1},
+		"Malformed": {MalformedRate: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := fake.Start(config)
+			defer server.Close()
+
+			client := sites.NewClient(sites.WithBaseURL(server.URL))
+
+			_, err := client.Search(context.Background(), "monorail")
+			require.Error(t, err, "The API should be faulted")
+
+			screenCap, err := client.Caption(context.Background(), "S04E12", "406990")
+			require.NoError(t, err, "The HTML endpoint should still work")
+			assert.Equal(t, "/img/S04E12/406990/medium.jpg", screenCap.ImagePath)
+			assert.Empty(t, screenCap.Caption, "The HTML fallback has no caption")
+
+			resp, err := server.Client().Get(server.URL + "/caption/S04E12/406990")
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package frinkiac
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/ai"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultMatchCandidates is how many search results have their captions fetched and scored
+	DefaultMatchCandidates = 5
+
+	// episodeBoost is added to the score of a scene from the season and episode the model named
+	episodeBoost = 0.15
+)
+
+// Match is a scene scored against the quote it was searched for
+type Match struct {
+	// Result is the scene's representative frame
+	Result    sites.SearchResult
+	Scene     Scene
+	ScreenCap *sites.ScreenCapResult
+	Season    int
+	Episode   int
+	// Similarity is how closely the caption matches the quote, from 0 to 1
+	Similarity float64
+	// Score ranks the matches, the similarity plus a boost if the episode is the one the model named
+	Score float64
+
+	// closeness compares the whole texts to break ties between captions that all contain the quote
+	closeness float64
+}
+
+// Matcher fetches the captions of search results and ranks them by how well they match a quote
+type Matcher struct {
+	Client *sites.Client
+	// Candidates is how many of the top scenes are scored, DefaultMatchCandidates if not set
+	Candidates int
+	// SceneGap is the longest pause between frames of a scene, DefaultSceneGap if not set
+	SceneGap time.Duration
+}
+
+// Rank groups the search results into scenes, scores the top scenes for quote and returns them best first.
+// Only the representative frame of each scene has its caption fetched, scenes found to share a line
+// of dialogue are then merged. Scenes whose caption can't be fetched are skipped, an error is only
+// returned if none could be.
+func (m Matcher) Rank(ctx context.Context, quote ai.QuoteResponse, results []sites.SearchResult) ([]Match, error) {
+	candidates := m.Candidates
+	if candidates <= 0 {
+		candidates = DefaultMatchCandidates
+	}
+
+	scenes := ClusterScenes(results, m.SceneGap)
+	scenes = scenes[:min(candidates, len(scenes))]
+
+	var matches []Match
+	var lastErr error
+	for _, scene := range scenes {
+		result := scene.Representative
+		season, episode, err := sites.GetSeasonAndEpisode(result.EpisodID)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		screenCap, err := m.Client.Caption(ctx, result.EpisodID, result.Timestamp)
+		if err != nil {
+			log.Warn().Err(err).Str("episode", string(result.EpisodID)).Str("timestamp", string(result.Timestamp)).Msg("unable to get screen cap to score")
+			lastErr = err
+			continue
+		}
+
+		match := Match{
+			Result:     result,
+			Scene:      scene,
+			ScreenCap:  screenCap,
+			Season:     season,
+			Episode:    episode,
+			Similarity: Similarity(quote.Quote, screenCap.Caption),
+			closeness:  levenshteinRatio(Normalize(quote.Quote), Normalize(screenCap.Caption)),
+		}
+
+		match.Score = match.Similarity
+		if quote.Season == season && quote.Episode == episode {
+			match.Score += episodeBoost
+		}
+
+		log.Debug().Str("quote", quote.Quote).Str("caption", screenCap.Caption).Float64("similarity", match.Similarity).Float64("score", match.Score).Msg("scored screen cap")
+		matches = mergeMatch(matches, match)
+	}
+
+	if len(matches) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("unable to score any screen caps: %w", lastErr)
+	}
+
+	// stable so equally good scenes keep Frinkiac's order
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].closeness > matches[j].closeness
+	})
+
+	return matches, nil
+}
+
+// mergeMatch adds match to matches, or if it shares a line of dialogue with one of them, merges
+// the scenes and keeps the better scoring frame
+func mergeMatch(matches []Match, match Match) []Match {
+	for i, existing := range matches {
+		if existing.Scene.Episode != match.Scene.Episode || !sharesSubtitle(existing.ScreenCap, match.ScreenCap) {
+			continue
+		}
+
+		scene := existing.Scene.merge(match.Scene)
+		if match.Score > existing.Score {
+			matches[i] = match
+		}
+		scene.Representative = matches[i].Result
+		matches[i].Scene = scene
+		return matches
+	}
+
+	return append(matches, match)
+}
+
+// sharesSubtitle reports whether two screen caps show any of the same subtitles
+func sharesSubtitle(a, b *sites.ScreenCapResult) bool {
+	for _, id := range a.SubtitleIDs {
+		if slices.Contains(b.SubtitleIDs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Similarity scores how closely caption matches quote from 0 to 1, ignoring case and punctuation.
+// It is the better of the token set ratio, which rewards a quote found within a longer caption,
+// and the Levenshtein ratio of the whole texts, which tolerates misspellings.
+func Similarity(quote, caption string) float64 {
+	quote, caption = Normalize(quote), Normalize(caption)
+	if quote == "" || caption == "" {
+		return 0
+	}
+
+	return max(tokenSetRatio(quote, caption), levenshteinRatio(quote, caption))
+}
+
+// Normalize lower cases text, drops apostrophes and turns other punctuation into spaces
+// so "D'oh! S-M-R-T" becomes "doh s m r t"
+func Normalize(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == '\'' || r == '’':
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// tokenSetRatio compares the words a and b share with the words each has on its own,
+// so a short quote scores highly against a caption that contains it
+func tokenSetRatio(a, b string) float64 {
+	aWords, bWords := wordSet(a), wordSet(b)
+
+	var shared, onlyA, onlyB []string
+	for word := range aWords {
+		if bWords[word] {
+			shared = append(shared, word)
+		} else {
+			onlyA = append(onlyA, word)
+		}
+	}
+	for word := range bWords {
+		if !aWords[word] {
+			onlyB = append(onlyB, word)
+		}
+	}
+	slices.Sort(shared)
+	slices.Sort(onlyA)
+	slices.Sort(onlyB)
+
+	base := strings.Join(shared, " ")
+	withA := strings.TrimSpace(base + " " + strings.Join(onlyA, " "))
+	withB := strings.TrimSpace(base + " " + strings.Join(onlyB, " "))
+
+	best := levenshteinRatio(withA, withB)
+	if base != "" {
+		best = max(best, levenshteinRatio(base, withA), levenshteinRatio(base, withB))
+	}
+	return best
+}
+
+// wordSet returns the distinct words of text
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(text) {
+		words[word] = true
+	}
+	return words
+}
+
+// levenshteinRatio is 1 minus the edit distance between a and b relative to the longer of them
+func levenshteinRatio(a, b string) float64 {
+	ar, br := []rune(a), []rune(b)
+	longest := max(len(ar), len(br))
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(ar, br))/float64(longest)
+}
+
+// levenshtein is the number of single rune insertions, deletions and substitutions turning a into b
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,88 @@
+package frinkiac
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/ai"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/fake"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSimilarity tests that captions are scored ignoring case and punctuation
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, "doh s m r t", Normalize("D'oh! S-M-R-T"))
+
+	assert.InDelta(t, 1, Similarity("i am so SMART", "I am so smart! I am so smart!"), 1e-9, "A quote within the caption should match fully")
+	assert.Greater(t, Similarity("Im so smrt", "I'm so smart!"), 0.8, "Misspellings should still score well")
+	assert.Less(t, Similarity("steamed hams", "Everything's coming up Milhouse!"), 0.4)
+	assert.Zero(t, Similarity("", "Monorail!"))
+}
+
+// TestMatcherRank tests that search results are ranked by their captions rather than search order
+func TestMatcherRank(t *testing.T) {
+	server := fake.Start(fake.Config{})
+	defer server.Close()
+
+	client := sites.NewClient(sites.WithBaseURL(server.URL), sites.WithLimiter(nil))
+
+	quote := ai.QuoteResponse{Quote: "Monorail! Monorail! Monorail!"}
+	results, err := client.Search(context.Background(), "monorail")
+	require.NoError(t, err)
+	require.Greater(t, len(results), 1)
+
+	matches, err := Matcher{Client: client}.Rank(context.Background(), quote, results)
+	require.NoError(t, err)
+	require.NotEmpty(t, matches)
+	assert.Equal(t, "Monorail! Monorail! Monorail!", matches[0].ScreenCap.Caption)
+	assert.InDelta(t, 1, matches[0].Similarity, 1e-9)
+	for i := 1; i < len(matches); i++ {
+		assert.GreaterOrEqual(t, matches[i-1].Score, matches[i].Score, "Matches should be sorted best first")
+	}
+
+	matches, err = Matcher{Client: client, Candidates: 1}.Rank(context.Background(), quote, results)
+	require.NoError(t, err)
+	assert.Len(t, matches, 1, "Only the candidates should be scored")
+}
+
+// TestMatcherEpisodeBoost tests that a scene from the episode the model named wins a tie
+func TestMatcherEpisodeBoost(t *testing.T) {
+	// every episode has the same caption so only the episode can tell them apart
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Frame":{"Episode":%q,"Timestamp":%s},"Subtitles":[{"Content":"Nerds! Nerds! Nerds!"}]}`, r.URL.Query().Get("e"), r.URL.Query().Get("t"))
+	}))
+	defer server.Close()
+
+	results := []sites.SearchResult{{EpisodID: "S16E01", Timestamp: "1000"}, {EpisodID: "S05E03", Timestamp: "2000"}}
+
+	matches, err := Matcher{Client: sites.NewClient(sites.WithBaseURL(server.URL))}.Rank(context.Background(), ai.QuoteResponse{Quote: "nerds", Season: 5, Episode: 3}, results)
+	require.NoError(t, err)
+	require.Len(t, matches, 2)
+	assert.Equal(t, 5, matches[0].Season)
+	assert.Equal(t, 3, matches[0].Episode)
+	assert.InDelta(t, matches[0].Similarity, matches[1].Similarity, 1e-9)
+	assert.Greater(t, matches[0].Score, matches[1].Score)
+}
+
+// TestMatcherMergesSubtitles tests that scenes showing the same line of dialogue are merged
+func TestMatcherMergesSubtitles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"Frame":{"Episode":"S07E21","Timestamp":%s},"Subtitles":[{"Id":41241,"Content":"Aurora borealis?"}]}`, r.URL.Query().Get("t"))
+	}))
+	defer server.Close()
+
+	// a long line whose frames are too far apart to be clustered by time
+	results := []sites.SearchResult{{EpisodID: "S07E21", Timestamp: "398700"}, {EpisodID: "S07E21", Timestamp: "403000"}}
+
+	matches, err := Matcher{Client: sites.NewClient(sites.WithBaseURL(server.URL))}.Rank(context.Background(), ai.QuoteResponse{Quote: "aurora borealis"}, results)
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, results, matches[0].Scene.Frames)
+	assert.Equal(t, 398700, matches[0].Scene.Start)
+	assert.Equal(t, 403000, matches[0].Scene.End)
+}
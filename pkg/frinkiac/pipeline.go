@@ -0,0 +1,103 @@
+package frinkiac
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/ai"
+)
+
+// DefaultWorkers is how many quotes a Pipeline resolves at once by default
+const DefaultWorkers = 4
+
+// Resolution is the outcome of resolving one quote
+type Resolution struct {
+	Quote ai.QuoteResponse
+	// Matches are the scenes found for the quote best first, empty if Frinkiac has none
+	Matches []Match
+	// Err is why the quote could not be resolved
+	Err error
+}
+
+// Best returns the best matching scene, false if there is none
+func (r Resolution) Best() (Match, bool) {
+	if len(r.Matches) == 0 {
+		return Match{}, false
+	}
+	return r.Matches[0], true
+}
+
+// Pipeline searches Frinkiac for quotes and ranks the scenes found, resolving several quotes at once
+type Pipeline struct {
+	Matcher Matcher
+	// Workers bounds how many quotes are resolved at once, DefaultWorkers if not set
+	Workers int
+}
+
+// Resolve finds the scenes for each quote. The resolutions are ordered most confident quote first, and quotes
+// of equal confidence keep their order, so the best quotes are started first and listed first.
+// A quote that fails has its error in its resolution rather than stopping the others.
+// If the context is cancelled the quotes not yet started fail with the context's error.
+func (p Pipeline) Resolve(ctx context.Context, quotes []ai.QuoteResponse) []Resolution {
+	workers := p.Workers
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	quotes = slices.Clone(quotes)
+	slices.SortStableFunc(quotes, func(a, b ai.QuoteResponse) int {
+		return cmp.Compare(b.Confidence, a.Confidence)
+	})
+
+	resolutions := make([]Resolution, len(quotes))
+	slots := make(chan struct{}, workers)
+
+	var wg sync.WaitGroup
+	for i, quote := range quotes {
+		resolutions[i].Quote = quote
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			resolutions[i].Err = ctx.Err()
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			resolutions[i].Matches, resolutions[i].Err = p.resolve(ctx, quote)
+		}()
+	}
+
+	wg.Wait()
+	return resolutions
+}
+
+// resolve searches for a single quote and ranks the scenes found
+func (p Pipeline) resolve(ctx context.Context, quote ai.QuoteResponse) ([]Match, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	results, err := p.Matcher.Client.Search(ctx, quote.Quote)
+	if err != nil {
+		return nil, fmt.Errorf("error searching for quote: %w", err)
+	}
+
+	if len(results) == 0 {
+		return nil, nil
+	}
+
+	matches, err := p.Matcher.Rank(ctx, quote, results)
+	if err != nil {
+		return nil, fmt.Errorf("error matching screen caps: %w", err)
+	}
+
+	return matches, nil
+}
@@ -0,0 +1,140 @@
+package frinkiac
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/ai"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/fake"
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPipelineResolve tests that quotes are resolved concurrently, within the worker limit and in order of confidence
+func TestPipelineResolve(t *testing.T) {
+	frinkiac := fake.NewServer(fake.DefaultCorpus(), fake.Config{Latency: 20 * time.Millisecond})
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/search") {
+			frinkiac.ServeHTTP(w, r)
+			return
+		}
+
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+
+		frinkiac.ServeHTTP(w, r)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	quotes := []ai.QuoteResponse{
+		{Quote: "I am so smart", Confidence: 0.9},
+		{Quote: "steamed hams", Confidence: 0.8},
+		{Quote: "cromulent", Confidence: 0.7},
+		{Quote: "Monorail! Monorail! Monorail!", Confidence: 0.6},
+		{Quote: "coming up Milhouse", Confidence: 0.5},
+	}
+
+	pipeline := Pipeline{Matcher: Matcher{Client: sites.NewClient(sites.WithBaseURL(server.URL), sites.WithLimiter(nil))}, Workers: 2}
+	resolutions := pipeline.Resolve(context.Background(), quotes)
+
+	require.Len(t, resolutions, len(quotes))
+	for i, resolution := range resolutions {
+		assert.Equal(t, quotes[i], resolution.Quote, "Resolutions should keep the order of sorted quotes")
+		require.NoError(t, resolution.Err)
+	}
+
+	best, ok := resolutions[1].Best()
+	require.True(t, ok)
+	assert.Equal(t, "Steamed hams.", best.ScreenCap.Caption)
+
+	_, ok = resolutions[2].Best()
+	assert.False(t, ok, "A quote Frinkiac doesn't know should have no scenes")
+
+	assert.Equal(t, 2, maxInFlight, "Searches should run concurrently up to the worker limit")
+}
+
+// TestPipelineResolveUnsorted tests that quotes are resolved most confident first, keeping the order of ties
+func TestPipelineResolveUnsorted(t *testing.T) {
+	frinkiac := fake.NewServer(fake.DefaultCorpus(), fake.Config{})
+
+	var mu sync.Mutex
+	var searched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/search") {
+			mu.Lock()
+			searched = append(searched, r.URL.Query().Get("q"))
+			mu.Unlock()
+		}
+		frinkiac.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	quotes := []ai.QuoteResponse{
+		{Quote: "cromulent", Confidence: 0.2},
+		{Quote: "steamed hams", Confidence: 0.9},
+		{Quote: "Nerds", Confidence: 0.5},
+		{Quote: "I am so smart", Confidence: 0.9},
+	}
+	original := slices.Clone(quotes)
+
+	pipeline := Pipeline{Matcher: Matcher{Client: sites.NewClient(sites.WithBaseURL(server.URL), sites.WithLimiter(nil))}, Workers: 1}
+	resolutions := pipeline.Resolve(context.Background(), quotes)
+
+	var resolved []string
+	for _, resolution := range resolutions {
+		require.NoError(t, resolution.Err)
+		resolved = append(resolved, resolution.Quote.Quote)
+	}
+
+	want := []string{"steamed hams", "I am so smart", "Nerds", "cromulent"}
+	assert.Equal(t, want, resolved, "Resolutions should be most confident first, ties in their original order")
+	assert.Equal(t, want, searched, "The most confident quotes should be searched first")
+	assert.Equal(t, original, quotes, "The caller's quotes should not be reordered")
+}
+
+// TestPipelineErrors tests that a failing quote doesn't stop the others and cancellation is respected
+func TestPipelineErrors(t *testing.T) {
+	frinkiac := fake.NewServer(fake.DefaultCorpus(), fake.Config{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") == "Cowabunga" {
+			http.Error(w, "wrong show", http.StatusBadRequest)
+			return
+		}
+		frinkiac.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	pipeline := Pipeline{Matcher: Matcher{Client: sites.NewClient(sites.WithBaseURL(server.URL), sites.WithLimiter(nil))}}
+
+	resolutions := pipeline.Resolve(context.Background(), []ai.QuoteResponse{{Quote: "Cowabunga"}, {Quote: "Nerds"}})
+	require.Len(t, resolutions, 2)
+	assert.Error(t, resolutions[0].Err)
+	require.NoError(t, resolutions[1].Err)
+	_, ok := resolutions[1].Best()
+	assert.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	resolutions = pipeline.Resolve(ctx, []ai.QuoteResponse{{Quote: "Nerds"}, {Quote: "Steamed hams"}})
+	require.Len(t, resolutions, 2)
+	for _, resolution := range resolutions {
+		assert.ErrorIs(t, resolution.Err, context.Canceled)
+	}
+}
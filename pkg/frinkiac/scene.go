@@ -0,0 +1,139 @@
+package frinkiac
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+)
+
+// DefaultSceneGap is the longest pause between two frames of the same scene
+const DefaultSceneGap = 2 * time.Second
+
+// Scene is a run of search results from one episode close enough in time to be the same moment
+type Scene struct {
+	Episode sites.EpisodeID
+	// Frames are the search results in the scene ordered by time
+	Frames []sites.SearchResult
+	// Representative is the frame shown for the scene
+	Representative sites.SearchResult
+	// Start and End are the timestamps of the first and last frames in milliseconds
+	Start int
+	End   int
+}
+
+// Duration is the time between the first and last frames
+func (s Scene) Duration() time.Duration {
+	return time.Duration(s.End-s.Start) * time.Millisecond
+}
+
+// String describes the scene's time range, e.g. "17:52.8-17:55.2 (3 frames)"
+func (s Scene) String() string {
+	return fmt.Sprintf("%s-%s (%d frames)", formatTimestamp(s.Start), formatTimestamp(s.End), len(s.Frames))
+}
+
+// ClusterScenes groups search results into scenes, starting a new scene whenever the gap to the
+// previous frame of the episode is longer than gap, DefaultSceneGap if not set.
+// Scenes are returned in the order their first frame appears in results so the search ranking is kept.
+// Results with a timestamp that isn't a number are a scene of their own.
+func ClusterScenes(results []sites.SearchResult, gap time.Duration) []Scene {
+	if gap <= 0 {
+		gap = DefaultSceneGap
+	}
+
+	type frame struct {
+		result sites.SearchResult
+		ms     int
+		rank   int
+	}
+
+	// rank is the position of the scene's best ranked frame in results
+	type rankedScene struct {
+		scene Scene
+		rank  int
+	}
+
+	var order []sites.EpisodeID
+	episodes := make(map[sites.EpisodeID][]frame)
+	var scenes []rankedScene
+
+	for rank, result := range results {
+		ms, err := result.Timestamp.Milliseconds()
+		if err != nil {
+			scenes = append(scenes, rankedScene{Scene{Episode: result.EpisodID, Frames: []sites.SearchResult{result}, Representative: result}, rank})
+			continue
+		}
+
+		if _, ok := episodes[result.EpisodID]; !ok {
+			order = append(order, result.EpisodID)
+		}
+		episodes[result.EpisodID] = append(episodes[result.EpisodID], frame{result: result, ms: ms, rank: rank})
+	}
+
+	for _, episode := range order {
+		frames := episodes[episode]
+		slices.SortStableFunc(frames, func(a, b frame) int { return a.ms - b.ms })
+
+		for start := 0; start < len(frames); {
+			end := start + 1
+			for end < len(frames) && time.Duration(frames[end].ms-frames[end-1].ms)*time.Millisecond <= gap {
+				end++
+			}
+
+			scene := Scene{Episode: episode, Start: frames[start].ms, End: frames[end-1].ms}
+			rank := frames[start].rank
+			for _, f := range frames[start:end] {
+				scene.Frames = append(scene.Frames, f.result)
+				rank = min(rank, f.rank)
+			}
+			scene.Representative = representative(scene.Frames)
+
+			scenes = append(scenes, rankedScene{scene, rank})
+			start = end
+		}
+	}
+
+	slices.SortFunc(scenes, func(a, b rankedScene) int { return a.rank - b.rank })
+
+	sorted := make([]Scene, len(scenes))
+	for i, ranked := range scenes {
+		sorted[i] = ranked.scene
+	}
+	return sorted
+}
+
+// merge adds the frames of other, another part of the same scene, to s
+func (s Scene) merge(other Scene) Scene {
+	s.Frames = append(slices.Clone(s.Frames), other.Frames...)
+	slices.SortStableFunc(s.Frames, func(a, b sites.SearchResult) int {
+		am, _ := a.Timestamp.Milliseconds()
+		bm, _ := b.Timestamp.Milliseconds()
+		return am - bm
+	})
+
+	first := true
+	for _, frame := range s.Frames {
+		ms, err := frame.Timestamp.Milliseconds()
+		if err != nil {
+			continue
+		}
+		if first {
+			s.Start, first = ms, false
+		}
+		s.End = ms
+	}
+
+	return s
+}
+
+// representative picks the middle frame of a scene, the earlier of the two middle frames if even,
+// as it is the most likely to show the line being spoken
+func representative(frames []sites.SearchResult) sites.SearchResult {
+	return frames[(len(frames)-1)/2]
+}
+
+// formatTimestamp formats milliseconds into an episode as minutes and seconds, e.g. 17:54.0
+func formatTimestamp(ms int) string {
+	return fmt.Sprintf("%d:%04.1f", ms/60000, float64(ms%60000)/1000)
+}
@@ -0,0 +1,43 @@
+package frinkiac
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/sites"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClusterScenes tests that nearby frames of an episode are grouped and scenes keep the search order
+func TestClusterScenes(t *testing.T) {
+	results := []sites.SearchResult{
+		{EpisodID: "S04E12", Timestamp: "408155"},
+		{EpisodID: "S05E03", Timestamp: "1074040"},
+		{EpisodID: "S04E12", Timestamp: "402150"},
+		{EpisodID: "S04E12", Timestamp: "406990"},
+		{EpisodID: "S04E12", Timestamp: "407500"},
+		{EpisodID: "S05E03", Timestamp: "1075208"},
+		{EpisodID: "S04E12", Timestamp: "not-a-number"},
+	}
+
+	scenes := ClusterScenes(results, 0)
+	require.Len(t, scenes, 4)
+
+	assert.Equal(t, sites.EpisodeID("S04E12"), scenes[0].Episode, "The scene of the best result should be first")
+	assert.Equal(t, []sites.SearchResult{results[3], results[4], results[0]}, scenes[0].Frames, "Frames should be ordered by time")
+	assert.Equal(t, results[4], scenes[0].Representative, "The middle frame should represent the scene")
+	assert.Equal(t, 406990, scenes[0].Start)
+	assert.Equal(t, 408155, scenes[0].End)
+	assert.Equal(t, 1165*time.Millisecond, scenes[0].Duration())
+	assert.Equal(t, "6:47.0-6:48.2 (3 frames)", scenes[0].String())
+
+	assert.Equal(t, []sites.SearchResult{results[1], results[5]}, scenes[1].Frames)
+	assert.Equal(t, results[1], scenes[1].Representative, "The earlier middle frame should represent an even scene")
+
+	assert.Equal(t, []sites.SearchResult{results[2]}, scenes[2].Frames, "Frames further apart than the gap should be separate scenes")
+	assert.Equal(t, []sites.SearchResult{results[6]}, scenes[3].Frames)
+
+	assert.Len(t, ClusterScenes(results, time.Minute), 3, "A longer gap should join the scenes of an episode")
+	assert.Empty(t, ClusterScenes(nil, 0))
+}
@@ -0,0 +1,390 @@
+package sites
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/filelock"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// DefaultCacheTTL is how long a cached response is used before it is revalidated, captions don't change
+	DefaultCacheTTL = 7 * 24 * time.Hour
+
+	// DefaultCacheMaxBytes bounds the size of the cached response bodies
+	DefaultCacheMaxBytes = 50 << 20
+)
+
+// cacheVersion is bumped when the cache file format changes, files of other versions are discarded
+const cacheVersion = 2
+
+// Cache keeps Frinkiac responses in a single file so repeated searches and captions don't hit the site.
+// Fresh responses are served from the file, stale ones are revalidated with their ETag or
+// Last-Modified and the least recently used responses are evicted once MaxBytes is reached.
+// The file is read once and changes are kept in memory until Flush, which merges them with
+// whatever other processes have written in the meantime.
+// A Cache is safe for concurrent use.
+type Cache struct {
+	// Path is the cache file
+	Path string
+	// TTL is how long a response is fresh
+	TTL time.Duration
+	// MaxBytes bounds the total size of the cached bodies, 0 for no limit
+	MaxBytes int64
+
+	mu     sync.Mutex
+	loaded bool
+	state  cacheFile
+	// dirty holds the keys stored or used since the last flush
+	dirty map[string]bool
+	// hits and misses are counted since the last flush
+	hits   int64
+	misses int64
+	now    func() time.Time
+}
+
+// CacheStats describes the contents of a Cache
+type CacheStats struct {
+	Path    string
+	Entries int
+	Bytes   int64
+	// Hits counts responses served from the cache, including revalidated ones
+	Hits int64
+	// Misses counts responses fetched from the site
+	Misses int64
+	Oldest time.Time
+	Newest time.Time
+}
+
+// cacheFile is the on disk format of the cache
+type cacheFile struct {
+	Version int                    `json:"version"`
+	Entries map[string]*cacheEntry `json:"entries"`
+	Hits    int64                  `json:"hits"`
+	Misses  int64                  `json:"misses"`
+}
+
+// cacheEntry is a cached response body and what is needed to revalidate it.
+// The body is kept as a string so the JSON and HTML responses are stored as is rather than base64 encoded.
+type cacheEntry struct {
+	Body         string    `json:"body"`
+	ContentType  string    `json:"content_type,omitempty"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	StoredAt     time.Time `json:"stored_at"`
+	UsedAt       time.Time `json:"used_at"`
+}
+
+// NewCache creates a Cache stored at path with the default TTL and size limit
+func NewCache(path string) *Cache {
+	return &Cache{Path: path, TTL: DefaultCacheTTL, MaxBytes: DefaultCacheMaxBytes}
+}
+
+// DefaultCache returns the cache in the user cache directory, e.g. ~/.cache/billy-bot/frinkiac.json
+func DefaultCache() *Cache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return NewCache(filepath.Join(dir, "billy-bot", "frinkiac.json"))
+}
+
+// Stats reports what is in the cache, including changes not yet flushed
+func (c *Cache) Stats() (CacheStats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return CacheStats{}, err
+	}
+
+	stats := CacheStats{Path: c.Path, Entries: len(c.state.Entries), Hits: c.state.Hits + c.hits, Misses: c.state.Misses + c.misses}
+	for _, entry := range c.state.Entries {
+		stats.Bytes += int64(len(entry.Body))
+		if stats.Oldest.IsZero() || entry.StoredAt.Before(stats.Oldest) {
+			stats.Oldest = entry.StoredAt
+		}
+		if entry.StoredAt.After(stats.Newest) {
+			stats.Newest = entry.StoredAt
+		}
+	}
+
+	return stats, nil
+}
+
+// Clear removes every cached response and the counters
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unlock, err := filelock.Lock(c.Path)
+	if err != nil {
+		return fmt.Errorf("error clearing frinkiac cache: %w", err)
+	}
+	defer unlock()
+
+	c.state = newCacheFile()
+	c.loaded = true
+	c.dirty = nil
+	c.hits, c.misses = 0, 0
+
+	if err := os.Remove(c.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error clearing frinkiac cache: %w", err)
+	}
+	return nil
+}
+
+// Flush writes the responses stored and used since the last flush to the cache file.
+// The file is locked and reread first so entries and counters written by other processes are kept.
+// It is a no-op if nothing has changed.
+func (c *Cache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.dirty) == 0 && c.hits == 0 && c.misses == 0 {
+		return nil
+	}
+
+	unlock, err := filelock.Lock(c.Path)
+	if err != nil {
+		return fmt.Errorf("error saving frinkiac cache: %w", err)
+	}
+	defer unlock()
+
+	merged, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	for key := range c.dirty {
+		entry, ok := c.state.Entries[key]
+		if !ok {
+			// evicted since it was used
+			continue
+		}
+
+		existing, ok := merged.Entries[key]
+		switch {
+		case !ok || entry.StoredAt.After(existing.StoredAt):
+			if ok && existing.UsedAt.After(entry.UsedAt) {
+				entry.UsedAt = existing.UsedAt
+			}
+			merged.Entries[key] = entry
+		case entry.UsedAt.After(existing.UsedAt):
+			existing.UsedAt = entry.UsedAt
+		}
+	}
+	merged.Hits += c.hits
+	merged.Misses += c.misses
+	evict(merged, c.MaxBytes)
+
+	if err := c.write(merged); err != nil {
+		return err
+	}
+
+	c.state = *merged
+	c.dirty = nil
+	c.hits, c.misses = 0, 0
+	return nil
+}
+
+// lookup returns the cached entry for key and whether it is still fresh
+func (c *Cache) lookup(key string) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		log.Warn().Err(err).Str("path", c.Path).Msg("unable to read frinkiac cache, ignoring it")
+		return nil, false
+	}
+
+	entry, ok := c.state.Entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	return entry, c.TTL <= 0 || c.clock().Sub(entry.StoredAt) < c.TTL
+}
+
+// hit records that entry was served for key, refreshing it if it was revalidated
+func (c *Cache) hit(key string, entry *cacheEntry, revalidated bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the entry may have been replaced by a flush since it was looked up
+	if current, ok := c.state.Entries[key]; ok {
+		entry = current
+	} else {
+		c.state.Entries[key] = entry
+	}
+
+	now := c.clock()
+	entry.UsedAt = now
+	if revalidated {
+		entry.StoredAt = now
+	}
+	c.hits++
+	c.markDirty(key)
+}
+
+// store caches a response body for key, evicting the least recently used entries if needed
+func (c *Cache) store(key string, body []byte, header http.Header) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.load(); err != nil {
+		return
+	}
+
+	now := c.clock()
+	c.misses++
+	c.state.Entries[key] = &cacheEntry{
+		Body:         string(body),
+		ContentType:  header.Get("Content-Type"),
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+		StoredAt:     now,
+		UsedAt:       now,
+	}
+	c.markDirty(key)
+	evict(&c.state, c.MaxBytes)
+}
+
+// markDirty records that key needs to be written on the next flush
+func (c *Cache) markDirty(key string) {
+	if c.dirty == nil {
+		c.dirty = make(map[string]bool)
+	}
+	c.dirty[key] = true
+}
+
+// evict drops the least recently used entries until the bodies fit in maxBytes
+func evict(state *cacheFile, maxBytes int64) {
+	if maxBytes <= 0 {
+		return
+	}
+
+	var size int64
+	keys := make([]string, 0, len(state.Entries))
+	for key, entry := range state.Entries {
+		size += int64(len(entry.Body))
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return state.Entries[keys[i]].UsedAt.Before(state.Entries[keys[j]].UsedAt)
+	})
+
+	for _, key := range keys {
+		if size <= maxBytes {
+			return
+		}
+		size -= int64(len(state.Entries[key].Body))
+		delete(state.Entries, key)
+		log.Debug().Str("key", key).Msg("evicted frinkiac cache entry")
+	}
+}
+
+// clock returns the current time, overridable in tests
+func (c *Cache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// load reads the cache file the first time the cache is used
+func (c *Cache) load() error {
+	if c.loaded {
+		return nil
+	}
+
+	state, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	c.state = *state
+	c.loaded = true
+	return nil
+}
+
+// read decodes the cache file, a missing, corrupt or outdated file is an empty cache
+func (c *Cache) read() (*cacheFile, error) {
+	state := newCacheFile()
+
+	data, err := os.ReadFile(c.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading frinkiac cache: %w", err)
+	}
+
+	var decoded cacheFile
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		// a corrupt cache is thrown away rather than breaking every request
+		log.Warn().Err(err).Str("path", c.Path).Msg("frinkiac cache is corrupt, starting a new one")
+		return &state, nil
+	}
+	if decoded.Version != cacheVersion {
+		log.Debug().Int("version", decoded.Version).Str("path", c.Path).Msg("frinkiac cache is from another version, starting a new one")
+		return &state, nil
+	}
+	if decoded.Entries == nil {
+		decoded.Entries = make(map[string]*cacheEntry)
+	}
+
+	return &decoded, nil
+}
+
+// write replaces the cache file atomically
+func (c *Cache) write(state *cacheFile) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error encoding frinkiac cache: %w", err)
+	}
+
+	if err := filelock.WriteFile(c.Path, data, 0o644); err != nil {
+		return fmt.Errorf("error writing frinkiac cache: %w", err)
+	}
+
+	return nil
+}
+
+// newCacheFile returns an empty cache of the current version
+func newCacheFile() cacheFile {
+	return cacheFile{Version: cacheVersion, Entries: make(map[string]*cacheEntry)}
+}
+
+// cachedResponse builds a 200 response for a cached body
+func cachedResponse(req *http.Request, entry *cacheEntry) *http.Response {
+	header := http.Header{}
+	if entry.ContentType != "" {
+		header.Set("Content-Type", entry.ContentType)
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,211 @@
+package sites
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/frinkiac/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCache tests that responses are served from the cache while fresh and revalidated once stale
+func TestCache(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("If-None-Match") == `"smart"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"smart"`)
+		fmt.Fprint(w, `[{"Id":514736,"Episode":"S05E03","Timestamp":1074040}]`)
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 6, 9, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "frinkiac.json")
+	cache := NewCache(path)
+	cache.TTL = time.Hour
+	cache.now = func() time.Time { return now }
+
+	client := NewClient(WithBaseURL(server.URL), WithCache(cache))
+	search := func() []SearchResult {
+		results, err := client.Search(context.Background(), "I am so smart")
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		return results
+	}
+
+	search()
+	search()
+	assert.Len(t, requests, 1, "A fresh response should come from the cache")
+
+	now = now.Add(2 * time.Hour)
+	assert.Equal(t, Timestamp("1074040"), search()[0].Timestamp, "A revalidated response should be served")
+	require.Len(t, requests, 2, "A stale response should be revalidated")
+	assert.Equal(t, `"smart"`, requests[1].Header.Get("If-None-Match"))
+
+	search()
+	assert.Len(t, requests, 2, "Revalidating should make the response fresh again")
+	assert.NoFileExists(t, path, "The cache should only be written when it is flushed")
+	require.NoError(t, client.Close())
+
+	// a new cache reads the same file
+	reopened := NewCache(path)
+	reopened.now = cache.now
+	stats, err := reopened.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, now, stats.Newest.UTC())
+
+	require.NoError(t, reopened.Clear())
+	stats, err = reopened.Stats()
+	require.NoError(t, err)
+	assert.Zero(t, stats.Entries)
+	assert.NoFileExists(t, path)
+}
+
+// TestCacheEviction tests that the least recently used responses are evicted past the size limit
+func TestCacheEviction(t *testing.T) {
+	now := time.Date(2025, 6, 9, 12, 0, 0, 0, time.UTC)
+	cache := NewCache(filepath.Join(t.TempDir(), "frinkiac.json"))
+	cache.MaxBytes = 10
+	cache.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	cache.store("a", []byte("aaaa"), http.Header{})
+	cache.store("b", []byte("bbbb"), http.Header{})
+
+	entry, fresh := cache.lookup("a")
+	require.True(t, fresh)
+	cache.hit("a", entry, false)
+
+	cache.store("c", []byte("cccc"), http.Header{})
+
+	_, fresh = cache.lookup("b")
+	assert.False(t, fresh, "The least recently used response should be evicted")
+	_, fresh = cache.lookup("a")
+	assert.True(t, fresh, "A recently used response should be kept")
+	_, fresh = cache.lookup("c")
+	assert.True(t, fresh)
+
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), stats.Bytes)
+}
+
+// TestCacheOnlyOK tests that failed responses and other methods are not cached
+func TestCacheOnlyOK(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithCache(NewCache(filepath.Join(t.TempDir(), "frinkiac.json"))))
+	for range 2 {
+		_, err := client.Search(context.Background(), "cromulent")
+		require.Error(t, err)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+// TestCacheSharedFile tests that caches in different processes sharing a file keep each other's entries
+func TestCacheSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "frinkiac.json")
+	first, second := NewCache(path), NewCache(path)
+
+	// both read the empty file before either writes
+	_, fresh := first.lookup("a")
+	assert.False(t, fresh)
+	_, fresh = second.lookup("b")
+	assert.False(t, fresh)
+
+	first.store("a", []byte(`{"quote":"d'oh"}`), http.Header{})
+	second.store("b", []byte("bbbb"), http.Header{})
+	entry, fresh := second.lookup("b")
+	require.True(t, fresh)
+	second.hit("b", entry, false)
+
+	require.NoError(t, first.Flush())
+	require.NoError(t, second.Flush())
+
+	reopened := NewCache(path)
+	stats, err := reopened.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Entries, "Neither cache should erase the other's entries")
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(2), stats.Misses)
+
+	entry, fresh = reopened.lookup("a")
+	require.True(t, fresh)
+	assert.Equal(t, `{"quote":"d'oh"}`, entry.Body)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `{\"quote\":\"d'oh\"}`, "Bodies should be stored as text, not base64")
+}
+
+// TestCacheMalformed tests that a truncated body from the site is not cached and served on later runs
+func TestCacheMalformed(t *testing.T) {
+	var handler http.Handler = fake.NewServer(fake.DefaultCorpus(), fake.Config{MalformedRate: 1})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "frinkiac.json")
+	client := NewClient(WithBaseURL(server.URL), WithLimiter(nil), WithCache(NewCache(path)))
+	_, err := client.Search(context.Background(), "I am so smart")
+	require.Error(t, err, "A truncated body should fail to decode")
+	require.NoError(t, client.Close())
+	assert.NoFileExists(t, path, "A truncated body should not be cached")
+
+	// the next run gets a good response from the site instead of the bad one from the cache
+	handler = fake.NewServer(fake.DefaultCorpus(), fake.Config{})
+	cache := NewCache(path)
+	client = NewClient(WithBaseURL(server.URL), WithLimiter(nil), WithCache(cache))
+	results, err := client.Search(context.Background(), "I am so smart")
+	require.NoError(t, err)
+	assert.NotEmpty(t, results)
+
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Misses, "The good body should be fetched from the site")
+	assert.Equal(t, 1, stats.Entries, "The good body should be cached")
+}
+
+// TestCacheUndecodable tests that a cached body that doesn't decode is fetched again and replaced
+func TestCacheUndecodable(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		fmt.Fprint(w, `[{"Id":514736,"Episode":"S05E10","Timestamp":1074040}]`)
+	}))
+	defer server.Close()
+
+	cache := NewCache(filepath.Join(t.TempDir(), "frinkiac.json"))
+	client := NewClient(WithBaseURL(server.URL), WithLimiter(nil), WithCache(cache))
+	cache.store(server.URL+"/api/search?q=smart", []byte(`[{"Id":5147`), http.Header{})
+
+	results, err := client.Search(context.Background(), "smart")
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, 1, calls, "A cached body that doesn't decode should not be served")
+
+	_, err = client.Search(context.Background(), "smart")
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls, "The replacement should be served from the cache")
+}
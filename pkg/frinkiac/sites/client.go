@@ -0,0 +1,405 @@
+// Package sites talks to Frinkiac and its sister screen cap sites, Morbotron and Master Of All Science,
+// which share the same API.
+package sites
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// BaseURL is the base URL for the Frinkiac website
+	BaseURL = "https://frinkiac.com"
+
+	// DefaultUserAgent identifies the bot to Frinkiac so its owners know who to contact
+	DefaultUserAgent = "billy-bot/1.0 (+https://github.com/kklipsch/billy-bot)"
+
+	// DefaultRetries is how many times a request is retried when Frinkiac asks us to back off
+	DefaultRetries = 3
+
+	// DefaultBackoff is the wait before the first retry when Frinkiac doesn't send Retry-After
+	DefaultBackoff = time.Second
+
+	// maxBackoff caps the wait between retries
+	maxBackoff = 30 * time.Second
+)
+
+// RetryPolicy controls how requests are retried when Frinkiac asks us to back off with a 429 or 503
+type RetryPolicy struct {
+	// Retries is how many times a request is retried
+	Retries int
+	// Backoff is the wait before the first retry when there is no Retry-After, it doubles each retry
+	Backoff time.Duration
+}
+
+// DefaultRetryPolicy returns the retry policy used by NewClient
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Retries: DefaultRetries, Backoff: DefaultBackoff}
+}
+
+// Client talks to Frinkiac or one of its sister sites. It paces its requests, retries when the site is busy and can cache
+// responses, so everything sharing a Client is polite to the site in the same way.
+// A Client is safe for concurrent use.
+type Client struct {
+	httpClient *http.Client
+	site       Site
+	baseURL    string
+	userAgent  string
+	limiter    *Limiter
+	cache      *Cache
+	logger     zerolog.Logger
+	retry      RetryPolicy
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// NewClient creates a Client for Frinkiac unless WithSite is given, rate limited and retrying when the site is busy
+// but without a cache unless WithCache is given
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		httpClient: NewHTTPClient(),
+		site:       Frinkiac,
+		baseURL:    Frinkiac.BaseURL,
+		userAgent:  DefaultUserAgent,
+		limiter:    NewLimiter(DefaultRate, DefaultBurst, DefaultMaxInFlight),
+		logger:     log.Logger,
+		retry:      DefaultRetryPolicy(),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// WithHTTPClient sends the requests with client
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithTransport sends the requests through transport, e.g. a cassette or a fake
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		client := *c.httpClient
+		client.Transport = transport
+		c.httpClient = &client
+	}
+}
+
+// WithSite points the client at one of the sites, e.g. Morbotron
+func WithSite(site Site) Option {
+	return func(c *Client) {
+		c.site = site
+		c.baseURL = strings.TrimSuffix(site.BaseURL, "/")
+	}
+}
+
+// WithBaseURL points the client at another copy of the site, e.g. a fake
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithUserAgent sets the User-Agent sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithLimiter paces the requests with limiter, nil for no limit.
+// Share a limiter between clients for the same site.
+func WithLimiter(limiter *Limiter) Option {
+	return func(c *Client) {
+		c.limiter = limiter
+	}
+}
+
+// WithCache keeps GET responses in cache, nil for no caching
+func WithCache(cache *Cache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+// WithLogger logs the requests to logger instead of the global logger
+func WithLogger(logger zerolog.Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithRetryPolicy sets how busy responses are retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// Site returns the site the client searches
+func (c *Client) Site() Site {
+	return c.site
+}
+
+// BaseURL returns the URL of the site the client talks to, image paths are relative to it
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Close saves the responses cached by the client so later runs can use them.
+// Call it once the client is done, the cache is only written then.
+func (c *Client) Close() error {
+	if c.cache == nil {
+		return nil
+	}
+	return c.cache.Flush()
+}
+
+// NewHTTPClient creates a new HTTP client with appropriate timeout for Frinkiac
+func NewHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout: 10 * time.Second,
+	}
+}
+
+// RequestOptions contains options for making a request
+type RequestOptions struct {
+	Method      string
+	Path        string
+	QueryParams url.Values
+	LogContext  map[string]interface{}
+	// NoCache skips the cache, e.g. for images
+	NoCache bool
+
+	// decode checks a successful body before it is cached or served from the cache, see getJSON
+	decode func(body []byte) error
+}
+
+// getJSON makes a GET request and decodes the JSON body into a T. The body is only cached once it has
+// decoded, so a truncated or malformed response is not served from the cache on later runs.
+func getJSON[T any](ctx context.Context, c *Client, opts RequestOptions) (T, error) {
+	var result T
+	opts.Method = http.MethodGet
+	opts.decode = func(body []byte) error {
+		var decoded T
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			return fmt.Errorf("error decoding JSON response: %w", err)
+		}
+		result = decoded
+		return nil
+	}
+
+	resp, err := c.doRequest(ctx, opts)
+	if err != nil {
+		return result, err
+	}
+	resp.Body.Close()
+
+	return result, nil
+}
+
+// doRequest makes an HTTP request and returns the response body
+func (c *Client) doRequest(ctx context.Context, opts RequestOptions) (*http.Response, error) {
+	// Construct URL
+	u, err := url.Parse(fmt.Sprintf("%s%s", c.baseURL, opts.Path))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing URL: %w", err)
+	}
+
+	// Add query parameters if provided
+	if opts.QueryParams != nil {
+		u.RawQuery = opts.QueryParams.Encode()
+	}
+
+	// Create request
+	requestURL := u.String()
+
+	// Create logger with context
+	logEvent := c.logger.Debug().Str("url", requestURL).Str("method", opts.Method)
+	for k, v := range opts.LogContext {
+		switch val := v.(type) {
+		case string:
+			logEvent = logEvent.Str(k, val)
+		case int:
+			logEvent = logEvent.Int(k, val)
+		case bool:
+			logEvent = logEvent.Bool(k, val)
+		default:
+			logEvent = logEvent.Interface(k, v)
+		}
+	}
+	logEvent.Msg("sending request to frinkiac")
+
+	// only GETs are cached, stale responses are sent with their validators to be revalidated
+	cache := c.cache
+	if opts.Method != http.MethodGet || opts.NoCache {
+		cache = nil
+	}
+
+	header := http.Header{}
+	var cached *cacheEntry
+	if cache != nil {
+		entry, fresh := cache.lookup(requestURL)
+		if entry != nil && opts.check(entry.Body) != nil {
+			// an unusable body is fetched again and replaced
+			c.logger.Debug().Str("url", requestURL).Msg("ignoring cached frinkiac response that doesn't decode")
+			entry, fresh = nil, false
+		}
+
+		if fresh {
+			c.logger.Debug().Str("url", requestURL).Msg("serving frinkiac response from cache")
+			cache.hit(requestURL, entry, false)
+			return cachedResponse(nil, entry), nil
+		}
+
+		if entry != nil {
+			cached = entry
+			if entry.ETag != "" {
+				header.Set("If-None-Match", entry.ETag)
+			}
+			if entry.LastModified != "" {
+				header.Set("If-Modified-Since", entry.LastModified)
+			}
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, opts.Method, requestURL, header)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
+			resp.Body.Close()
+			c.logger.Debug().Str("url", requestURL).Msg("cached frinkiac response revalidated")
+			cache.hit(requestURL, cached, true)
+			return cachedResponse(resp.Request, cached), nil
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			if cache == nil && opts.decode == nil {
+				return resp, nil
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("error reading response: %w", err)
+			}
+
+			if err := opts.check(string(body)); err != nil {
+				return nil, err
+			}
+
+			if cache != nil {
+				cache.store(requestURL, body, resp.Header)
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			return resp, nil
+		}
+
+		// Read the response body for error details
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if busy(resp.StatusCode) && attempt < c.retry.Retries {
+			wait := c.retry.backoff(attempt, resp.Header.Get("Retry-After"))
+			c.logger.Warn().Str("url", requestURL).Int("status", resp.StatusCode).Dur("wait", wait).Msg("frinkiac asked us to back off, retrying")
+
+			// everyone sharing the limiter backs off, not just this request
+			c.limiter.Pause(wait)
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if readErr != nil {
+			return nil, fmt.Errorf("unexpected status code: %d (failed to read response body: %v)",
+				resp.StatusCode, readErr)
+		}
+
+		return nil, fmt.Errorf("unexpected status code: %d, body: %s",
+			resp.StatusCode, string(body))
+	}
+}
+
+// check decodes a body if the request has a decoder
+func (o RequestOptions) check(body string) error {
+	if o.decode == nil {
+		return nil
+	}
+	return o.decode([]byte(body))
+}
+
+// send makes a single request once the limiter allows it, the limiter's in-flight slot is freed
+// when the response body is closed
+func (c *Client) send(ctx context.Context, method, requestURL string, header http.Header) (*http.Response, error) {
+	// Create request
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	maps.Copy(req.Header, header)
+
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error waiting to send request: %w", err)
+	}
+
+	// Send request
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	resp.Body = releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// busy reports whether a status asks us to slow down and try again
+func busy(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// backoff returns how long to wait before retrying, Retry-After if the site sent one
+func (p RetryPolicy) backoff(attempt int, retryAfter string) time.Duration {
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		return min(time.Duration(seconds)*time.Second, maxBackoff)
+	}
+	if at, err := http.ParseTime(retryAfter); err == nil {
+		return min(max(time.Until(at), 0), maxBackoff)
+	}
+
+	wait := p.Backoff
+	if wait <= 0 {
+		wait = DefaultBackoff
+	}
+	// double one step at a time so a large attempt can't overflow the shift into a negative wait
+	for ; attempt > 0 && wait < maxBackoff; attempt-- {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
@@ -0,0 +1,144 @@
+package sites
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// MaxClipDuration is the longest clip Frinkiac will render
+const MaxClipDuration = 10 * time.Second
+
+// ClipFormat is the kind of animation a clip is rendered as
+type ClipFormat string
+
+const (
+	// ClipGIF renders a clip as an animated GIF
+	ClipGIF ClipFormat = "gif"
+	// ClipMP4 renders a clip as an MP4 video
+	ClipMP4 ClipFormat = "mp4"
+)
+
+// Clip is a stretch of an episode, Start and End are milliseconds into the episode
+type Clip struct {
+	Episode EpisodeID
+	Start   int
+	End     int
+}
+
+// Duration returns how long the clip runs
+func (c Clip) Duration() time.Duration {
+	return time.Duration(c.End-c.Start) * time.Millisecond
+}
+
+// ClipRange returns the clip of the lines of dialogue shown in screenCap. Lines that run longer than
+// maxDuration are cut down to maxDuration around the frame, a maxDuration below 1 or above
+// MaxClipDuration means MaxClipDuration.
+func ClipRange(screenCap *ScreenCapResult, maxDuration time.Duration) (Clip, error) {
+	if maxDuration <= 0 || maxDuration > MaxClipDuration {
+		maxDuration = MaxClipDuration
+	}
+
+	if screenCap.SubtitleEnd <= screenCap.SubtitleStart {
+		return Clip{}, fmt.Errorf("no subtitle timings for screen cap %s%s %s", screenCap.Season, screenCap.Episode, screenCap.ID)
+	}
+
+	clip := Clip{
+		Episode: EpisodeID(screenCap.Season + screenCap.Episode),
+		Start:   screenCap.SubtitleStart,
+		End:     screenCap.SubtitleEnd,
+	}
+	if err := validateEpisodeFormat(string(clip.Episode)); err != nil {
+		return Clip{}, err
+	}
+
+	longest := int(maxDuration.Milliseconds())
+	if clip.End-clip.Start <= longest {
+		return clip, nil
+	}
+
+	// keep the frame in the middle of the clip without running past the lines
+	frame, err := strconv.Atoi(screenCap.ID)
+	if err != nil {
+		frame = clip.Start
+	}
+	clip.Start = min(max(frame-longest/2, clip.Start), clip.End-longest)
+	clip.End = clip.Start + longest
+
+	return clip, nil
+}
+
+// GetClipPath constructs the path of clip rendered as format, with text written on it if not empty
+func GetClipPath(clip Clip, format ClipFormat, text string) (string, error) {
+	path, query, err := clipRequest(clip, format, text)
+	if err != nil {
+		return "", err
+	}
+
+	if len(query) == 0 {
+		return path, nil
+	}
+	return path + "?" + query.Encode(), nil
+}
+
+// Clip downloads clip rendered as format, with text written on it if not empty
+func (c *Client) Clip(ctx context.Context, clip Clip, format ClipFormat, text string) ([]byte, error) {
+	path, query, err := clipRequest(clip, format, text)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, RequestOptions{
+		Method:      http.MethodGet,
+		Path:        path,
+		QueryParams: query,
+		LogContext: map[string]interface{}{
+			"episode": string(clip.Episode),
+			"start":   clip.Start,
+			"end":     clip.End,
+			"format":  string(format),
+		},
+		NoCache: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading clip: %w", err)
+	}
+
+	return data, nil
+}
+
+// clipRequest validates a clip and builds its path and query
+func clipRequest(clip Clip, format ClipFormat, text string) (string, url.Values, error) {
+	if format != ClipGIF && format != ClipMP4 {
+		return "", nil, fmt.Errorf("unknown clip format %q", format)
+	}
+
+	if err := validateEpisodeFormat(string(clip.Episode)); err != nil {
+		return "", nil, err
+	}
+
+	if clip.End <= clip.Start {
+		return "", nil, fmt.Errorf("clip ends at %d before it starts at %d", clip.End, clip.Start)
+	}
+
+	if clip.Duration() > MaxClipDuration {
+		return "", nil, fmt.Errorf("clip of %s is longer than the %s Frinkiac allows", clip.Duration(), MaxClipDuration)
+	}
+
+	var query url.Values
+	if text != "" {
+		query = url.Values{"b64lines": {encodeMemeText(text)}}
+	}
+
+	return fmt.Sprintf("/%s/%s/%d/%d.%s", format, clip.Episode, clip.Start, clip.End, format), query, nil
+}
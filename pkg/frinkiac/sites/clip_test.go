@@ -0,0 +1,92 @@
+package sites
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClipRange tests that clips cover the lines of dialogue without running past the longest clip
+func TestClipRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       string
+		start    int
+		end      int
+		max      time.Duration
+		expected Clip
+	}{
+		{"Lines", "1074040", 1072820, 1077720, 0, Clip{Episode: "S05E03", Start: 1072820, End: 1077720}},
+		{"Centered", "1080000", 1070000, 1090000, 0, Clip{Episode: "S05E03", Start: 1075000, End: 1085000}},
+		{"NearStart", "1071000", 1070000, 1090000, 4 * time.Second, Clip{Episode: "S05E03", Start: 1070000, End: 1074000}},
+		{"NearEnd", "1089000", 1070000, 1090000, 4 * time.Second, Clip{Episode: "S05E03", Start: 1086000, End: 1090000}},
+		{"CappedMax", "1080000", 1070000, 1090000, time.Minute, Clip{Episode: "S05E03", Start: 1075000, End: 1085000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			screenCap := &ScreenCapResult{Season: "S05", Episode: "E03", ID: tt.id, SubtitleStart: tt.start, SubtitleEnd: tt.end}
+
+			clip, err := ClipRange(screenCap, tt.max)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, clip)
+		})
+	}
+
+	_, err := ClipRange(&ScreenCapResult{Season: "S05", Episode: "E03", ID: "1074040"}, 0)
+	assert.Error(t, err, "A screen cap from the HTML page has no timings")
+}
+
+// TestGetClipPath tests the GIF and MP4 paths and that clips are validated
+func TestGetClipPath(t *testing.T) {
+	clip := Clip{Episode: "S05E03", Start: 1072820, End: 1077720}
+
+	path, err := GetClipPath(clip, ClipGIF, "")
+	require.NoError(t, err)
+	assert.Equal(t, "/gif/S05E03/1072820/1077720.gif", path)
+
+	path, err = GetClipPath(clip, ClipMP4, "S-M-R-T")
+	require.NoError(t, err)
+	u, err := url.Parse(path)
+	require.NoError(t, err)
+	assert.Equal(t, "/mp4/S05E03/1072820/1077720.mp4", u.Path)
+	lines, err := base64.URLEncoding.DecodeString(u.Query().Get("b64lines"))
+	require.NoError(t, err)
+	assert.Equal(t, "S-M-R-T", string(lines))
+
+	_, err = GetClipPath(clip, "avi", "")
+	assert.Error(t, err)
+
+	_, err = GetClipPath(Clip{Episode: "S05E03", Start: 1077720, End: 1072820}, ClipGIF, "")
+	assert.Error(t, err, "A clip should end after it starts")
+
+	_, err = GetClipPath(Clip{Episode: "S05E03", Start: 0, End: 60000}, ClipGIF, "")
+	assert.Error(t, err, "A clip should be no longer than Frinkiac allows")
+}
+
+// TestClip tests that the rendered clip is downloaded
+func TestClip(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.RequestURI()
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write([]byte("GIF89a"))
+	}))
+	defer server.Close()
+
+	clip := Clip{Episode: "S04E12", Start: 405000, End: 408000}
+	data, err := NewClient(WithBaseURL(server.URL)).Clip(context.Background(), clip, ClipGIF, "Monorail!")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("GIF89a"), data)
+
+	path, err := GetClipPath(clip, ClipGIF, "Monorail!")
+	require.NoError(t, err)
+	assert.Equal(t, path, requested)
+}
@@ -0,0 +1,151 @@
+package sites
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRate is how many requests per second are sent to Frinkiac by default
+	DefaultRate = 4
+	// DefaultBurst is how many requests can be sent at once after a quiet period by default
+	DefaultBurst = 4
+	// DefaultMaxInFlight is how many requests can be waiting on Frinkiac at once by default
+	DefaultMaxInFlight = 2
+)
+
+// Limiter keeps requests to Frinkiac polite. It is a token bucket refilled at a steady rate
+// combined with a cap on the requests in flight, and can be paused when the site asks us to back off.
+// A Limiter is safe for concurrent use and should be shared by everything talking to the same site.
+type Limiter struct {
+	rate     float64
+	burst    float64
+	inFlight chan struct{}
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	now         func() time.Time
+}
+
+// NewLimiter creates a Limiter allowing rate requests per second, bursts of up to burst requests
+// and at most maxInFlight requests at once. A rate of 0 or less or a maxInFlight below 1 means no limit,
+// fractional rates such as 0.5 requests per second are allowed.
+func NewLimiter(rate float64, burst, maxInFlight int) *Limiter {
+	l := &Limiter{
+		rate:   rate,
+		burst:  float64(max(burst, 1)),
+		tokens: float64(max(burst, 1)),
+		now:    time.Now,
+	}
+
+	if maxInFlight > 0 {
+		l.inFlight = make(chan struct{}, maxInFlight)
+	}
+
+	return l
+}
+
+// Acquire waits until a request may be sent. The returned release func must be called once the
+// request is finished to free its in-flight slot.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	if l.inFlight != nil {
+		select {
+		case l.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release = sync.OnceFunc(func() {
+		if l.inFlight != nil {
+			<-l.inFlight
+		}
+	})
+
+	if err := sleep(ctx, l.reserve()); err != nil {
+		release()
+		return nil, err
+	}
+
+	return release, nil
+}
+
+// Pause stops requests from being sent for d, e.g. when the site responds with Retry-After
+func (l *Limiter) Pause(d time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if until := l.now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// reserve takes a token and returns how long to wait before it can be used
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var wait time.Duration
+	if l.pausedUntil.After(now) {
+		wait = l.pausedUntil.Sub(now)
+	}
+
+	if l.rate <= 0 {
+		return wait
+	}
+
+	if !l.last.IsZero() {
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+
+	// tokens go negative while requests are queued, each waiting its turn
+	l.tokens--
+	if l.tokens < 0 {
+		wait = max(wait, time.Duration(-l.tokens/l.rate*float64(time.Second)))
+	}
+
+	return wait
+}
+
+// sleep waits for d or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseOnClose frees the in-flight slot of a request once its response body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+// Close implements io.Closer
+func (r releaseOnClose) Close() error {
+	defer r.release()
+	return r.ReadCloser.Close()
+}
@@ -0,0 +1,118 @@
+package sites
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLimiterRate tests that requests past the burst are spaced out at the rate
+func TestLimiterRate(t *testing.T) {
+	limiter := NewLimiter(20, 2, 0)
+
+	start := time.Now()
+	for range 4 {
+		release, err := limiter.Acquire(context.Background())
+		require.NoError(t, err)
+		release()
+	}
+
+	// the burst goes at once, the other two wait 50ms each
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+// TestLimiterFractionalRate tests that rates below one request per second are enforced and 0 is unlimited
+func TestLimiterFractionalRate(t *testing.T) {
+	now := time.Date(2025, 6, 9, 12, 0, 0, 0, time.UTC)
+
+	limiter := NewLimiter(0.5, 1, 0)
+	limiter.now = func() time.Time { return now }
+	assert.Zero(t, limiter.reserve())
+	assert.Equal(t, 2*time.Second, limiter.reserve(), "Half a request per second should wait two seconds")
+
+	unlimited := NewLimiter(0, 1, 0)
+	unlimited.now = limiter.now
+	for range 3 {
+		assert.Zero(t, unlimited.reserve())
+	}
+}
+
+// TestLimiterInFlight tests that no more than the maximum requests are in flight
+func TestLimiterInFlight(t *testing.T) {
+	limiter := NewLimiter(0, 1, 1)
+
+	release, err := limiter.Acquire(context.Background())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "A second request should wait for the first")
+
+	release()
+	release()
+
+	release, err = limiter.Acquire(context.Background())
+	require.NoError(t, err, "Releasing should free the slot")
+	release()
+
+	release, err = (*Limiter)(nil).Acquire(context.Background())
+	require.NoError(t, err, "A nil limiter should not limit")
+	release()
+}
+
+// TestDoRequestBackoff tests that busy responses are retried and the user agent is sent
+func TestDoRequestBackoff(t *testing.T) {
+	var userAgents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgents = append(userAgents, r.UserAgent())
+		switch len(userAgents) {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			fmt.Fprint(w, "[]")
+		}
+	}))
+	defer server.Close()
+
+	limiter := NewLimiter(0, 1, 1)
+	client := NewClient(WithBaseURL(server.URL), WithLimiter(limiter), WithRetryPolicy(RetryPolicy{Retries: 2, Backoff: time.Millisecond}))
+
+	results, err := client.Search(context.Background(), "d'oh")
+	require.NoError(t, err, "Busy responses should be retried")
+	assert.Empty(t, results)
+	assert.Equal(t, []string{DefaultUserAgent, DefaultUserAgent, DefaultUserAgent}, userAgents)
+
+	release, err := limiter.Acquire(context.Background())
+	require.NoError(t, err, "Every response should have freed its in-flight slot")
+	release()
+
+	userAgents = nil
+	client = NewClient(WithBaseURL(server.URL), WithUserAgent("test-agent"), WithRetryPolicy(RetryPolicy{Retries: 1, Backoff: time.Millisecond}))
+	_, err = client.Search(context.Background(), "d'oh")
+	require.Error(t, err, "Retries should give up")
+	assert.Contains(t, err.Error(), "503")
+	assert.Equal(t, []string{"test-agent", "test-agent"}, userAgents)
+}
+
+// TestBackoff tests the wait before a retry
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond}
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(0, ""))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(2, ""), "The backoff should double each retry")
+	assert.Equal(t, 5*time.Second, policy.backoff(0, "5"), "Retry-After should be used")
+	assert.Equal(t, maxBackoff, policy.backoff(0, "3600"), "Long waits should be capped")
+	assert.Equal(t, maxBackoff, policy.backoff(20, ""))
+	assert.Equal(t, maxBackoff, policy.backoff(64, ""), "A large attempt should not overflow")
+	assert.Equal(t, maxBackoff, policy.backoff(1000, ""))
+}
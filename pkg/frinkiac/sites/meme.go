@@ -0,0 +1,109 @@
+package sites
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// MemeLineWidth is about how many characters fit on a line of a meme before Frinkiac runs off the frame
+const MemeLineWidth = 25
+
+// WrapMemeText splits text into lines of at most width characters, breaking between words where it can.
+// Line breaks already in text are kept and a width below 1 means MemeLineWidth.
+func WrapMemeText(text string, width int) []string {
+	if width < 1 {
+		width = MemeLineWidth
+	}
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			// words too long for a line of their own are split
+			for len([]rune(word)) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, string([]rune(word)[:width]))
+				word = string([]rune(word)[width:])
+			}
+
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// GetMemePath constructs the path of the frame of episodeID at timestamp with text written on it.
+// The text is wrapped to MemeLineWidth and sent base64url encoded as Frinkiac expects.
+func GetMemePath(episodeID EpisodeID, timestamp Timestamp, text string) (string, error) {
+	path, query, err := memeRequest(episodeID, timestamp, text)
+	if err != nil {
+		return "", err
+	}
+
+	return path + "?" + query.Encode(), nil
+}
+
+// Meme downloads the JPEG of the frame of episodeID at timestamp with text written on it
+func (c *Client) Meme(ctx context.Context, episodeID EpisodeID, timestamp Timestamp, text string) ([]byte, error) {
+	path, query, err := memeRequest(episodeID, timestamp, text)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, RequestOptions{
+		Method:      http.MethodGet,
+		Path:        path,
+		QueryParams: query,
+		LogContext: map[string]interface{}{
+			"episode":   string(episodeID),
+			"timestamp": string(timestamp),
+			"text":      text,
+		},
+		NoCache: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	image, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading meme: %w", err)
+	}
+
+	return image, nil
+}
+
+// memeRequest builds the path and query of a meme
+func memeRequest(episodeID EpisodeID, timestamp Timestamp, text string) (string, url.Values, error) {
+	if err := validateEpisodeFormat(string(episodeID)); err != nil {
+		return "", nil, err
+	}
+
+	query := url.Values{"b64lines": {encodeMemeText(text)}}
+	return fmt.Sprintf("/meme/%s/%s.jpg", episodeID, timestamp), query, nil
+}
+
+// encodeMemeText wraps text and base64url encodes it for the b64lines parameter
+func encodeMemeText(text string) string {
+	lines := strings.Join(WrapMemeText(text, MemeLineWidth), "\n")
+	return base64.URLEncoding.EncodeToString([]byte(lines))
+}
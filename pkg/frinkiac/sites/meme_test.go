@@ -0,0 +1,73 @@
+package sites
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWrapMemeText tests that meme text is wrapped between words to the line width
+func TestWrapMemeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		width    int
+		expected []string
+	}{
+		{"Short", "D'oh!", 0, []string{"D'oh!"}},
+		{"Wrapped", "I am so smart! I am so smart! S-M-R-T!", 0, []string{"I am so smart! I am so", "smart! S-M-R-T!"}},
+		{"Exact", "abcde fghij", 5, []string{"abcde", "fghij"}},
+		{"LongWord", "a embiggens", 4, []string{"a", "embi", "ggen", "s"}},
+		{"Newlines", "Steamed hams\n\nAurora  borealis", 0, []string{"Steamed hams", "", "Aurora borealis"}},
+		{"Runes", "ñññ ñññ", 3, []string{"ñññ", "ñññ"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, WrapMemeText(tt.text, tt.width))
+		})
+	}
+}
+
+// TestGetMemePath tests that the meme text is sent base64url encoded
+func TestGetMemePath(t *testing.T) {
+	path, err := GetMemePath("S05E03", "1074040", "I am so smart! I am so smart! S-M-R-T!")
+	require.NoError(t, err)
+
+	u, err := url.Parse(path)
+	require.NoError(t, err)
+	assert.Equal(t, "/meme/S05E03/1074040.jpg", u.Path)
+
+	lines, err := base64.URLEncoding.DecodeString(u.Query().Get("b64lines"))
+	require.NoError(t, err)
+	assert.Equal(t, "I am so smart! I am so\nsmart! S-M-R-T!", string(lines))
+
+	_, err = GetMemePath("S5E3", "1074040", "smart")
+	assert.ErrorIs(t, err, ErrInvalidEpisodeFormat)
+}
+
+// TestMeme tests that the rendered meme is downloaded
+func TestMeme(t *testing.T) {
+	var requested *url.URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte("jpeg"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL))
+	image, err := client.Meme(context.Background(), "S04E12", "406990", "Monorail?")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("jpeg"), image)
+
+	path, err := GetMemePath("S04E12", "406990", "Monorail?")
+	require.NoError(t, err)
+	assert.Equal(t, path, requested.RequestURI())
+}
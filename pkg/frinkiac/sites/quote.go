@@ -1,11 +1,9 @@
-package http
+package sites
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strconv"
 )
@@ -25,6 +23,15 @@ func (t *Timestamp) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Milliseconds returns the timestamp as milliseconds into the episode
+func (t Timestamp) Milliseconds() (int, error) {
+	ms, err := strconv.Atoi(string(t))
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q: %w", string(t), err)
+	}
+	return ms, nil
+}
+
 // EpisodeID represents a Simpsons episode identifier in the format S##E##
 type EpisodeID string
 
@@ -83,8 +90,8 @@ func GetImagePath(episodeID EpisodeID, timestamp Timestamp) (string, error) {
 	return fmt.Sprintf("/img/%s/%s/medium.jpg", episodeID, timestamp), nil
 }
 
-// GetQuote searches for a quote on Frinkiac and returns the results
-func GetQuote(ctx context.Context, client *http.Client, config Config, quote string) ([]SearchResult, error) {
+// Search searches the captions on Frinkiac for a quote and returns the matching frames
+func (c *Client) Search(ctx context.Context, quote string) ([]SearchResult, error) {
 	// Set up query parameters
 	queryParams := url.Values{}
 	queryParams.Set("q", quote)
@@ -94,23 +101,27 @@ func GetQuote(ctx context.Context, client *http.Client, config Config, quote str
 		"quote": quote,
 	}
 
-	// Make the request
-	resp, err := doRequest(ctx, client, config, RequestOptions{
-		Method:      http.MethodGet,
+	// Make the request and parse the JSON response
+	return getJSON[[]SearchResult](ctx, c, RequestOptions{
 		Path:        "/api/search",
 		QueryParams: queryParams,
 		LogContext:  logContext,
 	})
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+}
 
-	// Parse JSON response
-	var apiResults []SearchResult
-	if err := json.NewDecoder(resp.Body).Decode(&apiResults); err != nil {
-		return nil, fmt.Errorf("error decoding JSON response: %w", err)
+// Episode returns the frames of an episode between two timestamps, e.g. to make a GIF of a scene
+func (c *Client) Episode(ctx context.Context, episodeID EpisodeID, start, end Timestamp) ([]SearchResult, error) {
+	if err := validateEpisodeFormat(string(episodeID)); err != nil {
+		return nil, err
 	}
 
-	return apiResults, nil
+	// Make the request and parse the JSON response
+	return getJSON[[]SearchResult](ctx, c, RequestOptions{
+		Path: fmt.Sprintf("/api/episode/%s/%s/%s", episodeID, start, end),
+		LogContext: map[string]interface{}{
+			"episode": string(episodeID),
+			"start":   string(start),
+			"end":     string(end),
+		},
+	})
 }
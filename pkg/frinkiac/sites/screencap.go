@@ -1,14 +1,14 @@
-package http
+package sites
 
 import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
-
-	"github.com/rs/zerolog/log"
 )
 
 // ScreenCapResult represents the result of a screen cap request
@@ -18,6 +18,12 @@ type ScreenCapResult struct {
 	Season    string
 	Episode   string
 	ID        string
+	// SubtitleIDs identify the lines of dialogue in the caption, frames of the same scene share them
+	SubtitleIDs []int
+	// SubtitleStart and SubtitleEnd are when the lines of the caption are shown, in milliseconds
+	// into the episode, both 0 if not known
+	SubtitleStart int
+	SubtitleEnd   int
 }
 
 // APICaption represents the response from the Frinkiac API caption endpoint
@@ -54,8 +60,13 @@ type APICaption struct {
 	} `json:"Nearby"`
 }
 
-// GetScreenCap gets a screen cap from Frinkiac
-func GetScreenCap(ctx context.Context, client *http.Client, config Config, season, episode int, timestamp Timestamp) (*ScreenCapResult, error) {
+// Caption gets the screen cap of an episode at a timestamp from Frinkiac
+func (c *Client) Caption(ctx context.Context, episodeID EpisodeID, timestamp Timestamp) (*ScreenCapResult, error) {
+	season, episode, err := GetSeasonAndEpisode(episodeID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Convert timestamp to string for internal functions
 	id := string(timestamp)
 
@@ -64,17 +75,64 @@ func GetScreenCap(ctx context.Context, client *http.Client, config Config, seaso
 	episodeStr := fmt.Sprintf("E%02d", episode)
 
 	// First try the JSON API endpoint
-	result, err := getScreenCapFromAPI(ctx, client, config, seasonStr, episodeStr, id)
+	result, err := c.getScreenCapFromAPI(ctx, seasonStr, episodeStr, id)
 	if err != nil {
 		// If the API endpoint fails, fall back to the HTML endpoint
-		log.Info().Str("season", seasonStr).Str("episode", episodeStr).Str("id", id).Msg("API endpoint failed, falling back to HTML endpoint")
-		return getScreenCapFromHTML(ctx, client, config, seasonStr, episodeStr, id)
+		c.logger.Info().Str("season", seasonStr).Str("episode", episodeStr).Str("id", id).Msg("API endpoint failed, falling back to HTML endpoint")
+		return c.getScreenCapFromHTML(ctx, seasonStr, episodeStr, id)
 	}
 	return result, nil
 }
 
+// Random gets the screen cap of a random frame from Frinkiac
+func (c *Client) Random(ctx context.Context) (*ScreenCapResult, error) {
+	resp, err := c.doRequest(ctx, RequestOptions{
+		Method:  http.MethodGet,
+		Path:    "/api/random",
+		NoCache: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var apiCaption APICaption
+	if err := json.NewDecoder(resp.Body).Decode(&apiCaption); err != nil {
+		return nil, fmt.Errorf("error decoding JSON response: %w", err)
+	}
+
+	season := fmt.Sprintf("S%02d", apiCaption.Episode.Season)
+	episode := fmt.Sprintf("E%02d", apiCaption.Episode.EpisodeNumber)
+	return newScreenCapResult(apiCaption, season, episode, strconv.Itoa(apiCaption.Frame.Timestamp)), nil
+}
+
+// Image downloads the medium sized image of an episode at a timestamp
+func (c *Client) Image(ctx context.Context, episodeID EpisodeID, timestamp Timestamp) ([]byte, error) {
+	path, err := GetImagePath(episodeID, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, RequestOptions{
+		Method:  http.MethodGet,
+		Path:    path,
+		NoCache: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	image, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading image: %w", err)
+	}
+
+	return image, nil
+}
+
 // getScreenCapFromAPI gets a screen cap from Frinkiac using the JSON API
-func getScreenCapFromAPI(ctx context.Context, client *http.Client, config Config, season, episode, id string) (*ScreenCapResult, error) {
+func (c *Client) getScreenCapFromAPI(ctx context.Context, season, episode, id string) (*ScreenCapResult, error) {
 	// Combine season and episode for the 'e' parameter (e.g., S16E01)
 	episodeKey := fmt.Sprintf("%s%s", season, episode)
 
@@ -90,9 +148,8 @@ func getScreenCapFromAPI(ctx context.Context, client *http.Client, config Config
 		"id":      id,
 	}
 
-	// Make the request
-	resp, err := doRequest(ctx, client, config, RequestOptions{
-		Method:      http.MethodGet,
+	// Make the request and parse the JSON response
+	apiCaption, err := getJSON[APICaption](ctx, c, RequestOptions{
 		Path:        "/api/caption",
 		QueryParams: queryParams,
 		LogContext:  logContext,
@@ -100,41 +157,15 @@ func getScreenCapFromAPI(ctx context.Context, client *http.Client, config Config
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// Parse JSON response
-	var apiCaption APICaption
-	if err := json.NewDecoder(resp.Body).Decode(&apiCaption); err != nil {
-		return nil, fmt.Errorf("error decoding JSON response: %w", err)
-	}
 
-	// Extract caption text from subtitles
-	var captionBuilder strings.Builder
-	for _, subtitle := range apiCaption.Subtitles {
-		if captionBuilder.Len() > 0 {
-			captionBuilder.WriteString(" ")
-		}
-		captionBuilder.WriteString(subtitle.Content)
-	}
-	caption := captionBuilder.String()
-
-	// Construct the image path
-	imagePath := fmt.Sprintf("/img/%s/%d/medium.jpg", apiCaption.Frame.Episode, apiCaption.Frame.Timestamp)
-
-	result := &ScreenCapResult{
-		ImagePath: imagePath,
-		Caption:   caption,
-		Season:    season,
-		Episode:   episode,
-		ID:        id,
-	}
+	result := newScreenCapResult(apiCaption, season, episode, id)
 
-	log.Debug().Str("season", season).Str("episode", episode).Str("id", id).Str("caption", result.Caption).Msg("parsed screen cap result from frinkiac API")
+	c.logger.Debug().Str("season", season).Str("episode", episode).Str("id", id).Str("caption", result.Caption).Msg("parsed screen cap result from frinkiac API")
 	return result, nil
 }
 
 // getScreenCapFromHTML gets a screen cap from Frinkiac using the HTML endpoint
-func getScreenCapFromHTML(ctx context.Context, client *http.Client, config Config, season, episode, id string) (*ScreenCapResult, error) {
+func (c *Client) getScreenCapFromHTML(ctx context.Context, season, episode, id string) (*ScreenCapResult, error) {
 	// Set up log context
 	logContext := map[string]interface{}{
 		"season":  season,
@@ -145,7 +176,7 @@ func getScreenCapFromHTML(ctx context.Context, client *http.Client, config Confi
 
 	// Make the request
 	path := fmt.Sprintf("/caption/%s%s/%s", season, episode, id)
-	resp, err := doRequest(ctx, client, config, RequestOptions{
+	resp, err := c.doRequest(ctx, RequestOptions{
 		Method:     http.MethodGet,
 		Path:       path,
 		LogContext: logContext,
@@ -167,6 +198,42 @@ func getScreenCapFromHTML(ctx context.Context, client *http.Client, config Confi
 		ID:        id,
 	}
 
-	log.Debug().Str("season", season).Str("episode", episode).Str("id", id).Str("caption", result.Caption).Msg("created screen cap result from frinkiac HTML endpoint")
+	c.logger.Debug().Str("season", season).Str("episode", episode).Str("id", id).Str("caption", result.Caption).Msg("created screen cap result from frinkiac HTML endpoint")
 	return result, nil
 }
+
+// newScreenCapResult builds the screen cap for a caption from the API
+func newScreenCapResult(apiCaption APICaption, season, episode, id string) *ScreenCapResult {
+	// Extract caption text from subtitles
+	var captionBuilder strings.Builder
+	var subtitleIDs []int
+	start, end := 0, 0
+	for i, subtitle := range apiCaption.Subtitles {
+		if captionBuilder.Len() > 0 {
+			captionBuilder.WriteString(" ")
+		}
+		captionBuilder.WriteString(subtitle.Content)
+		subtitleIDs = append(subtitleIDs, subtitle.ID)
+
+		if i == 0 || subtitle.StartTimestamp < start {
+			start = subtitle.StartTimestamp
+		}
+		end = max(end, subtitle.EndTimestamp)
+	}
+	caption := captionBuilder.String()
+
+	// Construct the image path
+	imagePath := fmt.Sprintf("/img/%s/%d/medium.jpg", apiCaption.Frame.Episode, apiCaption.Frame.Timestamp)
+
+	return &ScreenCapResult{
+		ImagePath:   imagePath,
+		Caption:     caption,
+		Season:      season,
+		Episode:     episode,
+		ID:          id,
+		SubtitleIDs: subtitleIDs,
+
+		SubtitleStart: start,
+		SubtitleEnd:   end,
+	}
+}
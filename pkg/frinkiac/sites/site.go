@@ -0,0 +1,51 @@
+package sites
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Site is a screen cap search site. Frinkiac and its sister sites share the same API and pages,
+// only the show and the URL differ.
+type Site struct {
+	// Key names the site's show on the command line, e.g. simpsons
+	Key string
+	// Name is the site's name, e.g. Frinkiac
+	Name string
+	// Show is the show the site has the screen caps of, e.g. The Simpsons
+	Show string
+	// BaseURL is the URL of the site
+	BaseURL string
+	// BaseURLEnv is the environment variable that points the site at another copy, e.g. a fake
+	BaseURLEnv string
+}
+
+var (
+	// Frinkiac searches The Simpsons
+	Frinkiac = Site{Key: "simpsons", Name: "Frinkiac", Show: "The Simpsons", BaseURL: BaseURL, BaseURLEnv: "FRINKIAC_BASE_URL"}
+
+	// Morbotron searches Futurama
+	Morbotron = Site{Key: "futurama", Name: "Morbotron", Show: "Futurama", BaseURL: "https://morbotron.com", BaseURLEnv: "MORBOTRON_BASE_URL"}
+
+	// MasterOfAllScience searches Rick and Morty
+	MasterOfAllScience = Site{Key: "rickandmorty", Name: "Master Of All Science", Show: "Rick and Morty", BaseURL: "https://masterofallscience.com", BaseURLEnv: "MASTEROFALLSCIENCE_BASE_URL"}
+
+	// Sites are the known sites
+	Sites = []Site{Frinkiac, Morbotron, MasterOfAllScience}
+)
+
+// String returns the site's name
+func (s Site) String() string {
+	return s.Name
+}
+
+// SiteForShow returns the site for a show key such as futurama
+func SiteForShow(key string) (Site, error) {
+	for _, site := range Sites {
+		if strings.EqualFold(site.Key, key) {
+			return site, nil
+		}
+	}
+
+	return Site{}, fmt.Errorf("unknown show %q", key)
+}
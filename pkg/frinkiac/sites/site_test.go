@@ -0,0 +1,33 @@
+package sites
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSiteForShow tests that shows are looked up by their key
+func TestSiteForShow(t *testing.T) {
+	site, err := SiteForShow("Futurama")
+	require.NoError(t, err)
+	assert.Equal(t, Morbotron, site)
+
+	_, err = SiteForShow("itchyandscratchy")
+	assert.Error(t, err)
+}
+
+// TestWithSite tests that a client is pointed at its site unless the base URL is overridden
+func TestWithSite(t *testing.T) {
+	client := NewClient()
+	assert.Equal(t, Frinkiac, client.Site())
+	assert.Equal(t, "https://frinkiac.com", client.BaseURL())
+
+	client = NewClient(WithSite(MasterOfAllScience))
+	assert.Equal(t, MasterOfAllScience, client.Site())
+	assert.Equal(t, "https://masterofallscience.com", client.BaseURL())
+
+	client = NewClient(WithSite(Morbotron), WithBaseURL("http://localhost:8080/"))
+	assert.Equal(t, Morbotron, client.Site(), "A fake of the site should keep the site")
+	assert.Equal(t, "http://localhost:8080", client.BaseURL())
+}
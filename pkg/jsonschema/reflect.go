@@ -0,0 +1,359 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// Reflect builds a Schema describing how T is encoded by encoding/json.
+//
+// Struct fields follow their json tags: renamed and skipped fields are honoured and fields
+// without omitempty are required. Additional keywords are read from a jsonschema tag holding
+// comma separated key=value pairs, for example:
+//
+//	Confidence float64 `json:"confidence" jsonschema:"description=How sure the model is,minimum=0,maximum=1"`
+//
+// Supported keys are title, description, format, pattern, enum (repeat it for each value),
+// default, minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, minLength,
+// maxLength, minItems, maxItems, uniqueItems and required. Commas inside a value are escaped as \,.
+//
+// Recursive struct types are placed in $defs and referenced with $ref.
+// Reflect panics if a jsonschema tag cannot be parsed, as that is a programming error.
+func Reflect[T any]() *Schema {
+	return ReflectType(reflect.TypeFor[T]())
+}
+
+// ReflectType builds a Schema for t, see Reflect.
+func ReflectType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	r := &reflector{
+		root:      t,
+		defs:      make(map[string]*Schema),
+		names:     make(map[reflect.Type]string),
+		visiting:  make(map[reflect.Type]bool),
+		recursive: make(map[reflect.Type]bool),
+	}
+
+	schema := r.reflect(t)
+	if len(r.defs) > 0 {
+		schema.Defs = r.defs
+	}
+	return schema
+}
+
+// reflector holds the state of a single Reflect call
+type reflector struct {
+	root      reflect.Type
+	defs      map[string]*Schema
+	names     map[reflect.Type]string
+	visiting  map[reflect.Type]bool
+	recursive map[reflect.Type]bool
+}
+
+// reflect builds the schema for a single type
+func (r *reflector) reflect(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return NewBooleanSchema()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return NewIntegerSchema()
+	case reflect.Float32, reflect.Float64:
+		return NewNumberSchema()
+	case reflect.String:
+		return NewStringSchema()
+	case reflect.Slice, reflect.Array:
+		// encoding/json writes byte slices as base64 strings
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return NewStringSchema()
+		}
+		return NewArraySchema(r.reflect(t.Elem()))
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: r.reflect(t.Elem()),
+		}
+	case reflect.Struct:
+		return r.reflectStruct(t)
+	default:
+		// interfaces and anything else can hold any value
+		return &Schema{}
+	}
+}
+
+// reflectStruct builds the schema for a struct, moving it to $defs if it refers to itself
+func (r *reflector) reflectStruct(t reflect.Type) *Schema {
+	// only recursive types are named, so other types sharing their name don't number them
+	if r.recursive[t] {
+		if _, ok := r.defs[r.defName(t)]; ok {
+			return &Schema{Ref: r.defRef(t)}
+		}
+	}
+
+	if r.visiting[t] {
+		if t == r.root {
+			return &Schema{Ref: "#"}
+		}
+		r.recursive[t] = true
+		return &Schema{Ref: r.defRef(t)}
+	}
+
+	r.visiting[t] = true
+	schema := NewObjectSchema(make(map[string]*Schema), nil)
+	schema.AdditionalProperties = false
+	r.addFields(schema, t)
+	delete(r.visiting, t)
+
+	if r.recursive[t] {
+		r.defs[r.defName(t)] = schema
+		return &Schema{Ref: r.defRef(t)}
+	}
+
+	return schema
+}
+
+// addFields adds the properties of a struct's fields to schema, flattening embedded structs
+// the same way encoding/json does.
+func (r *reflector) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			r.addFields(schema, fieldType)
+			continue
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		var property *Schema
+		if hasOption(opts, "string") {
+			property = NewStringSchema()
+		} else {
+			property = r.reflect(field.Type)
+		}
+
+		required := !hasOption(opts, "omitempty") && !hasOption(opts, "omitzero")
+		if tag, ok := field.Tag.Lookup("jsonschema"); ok {
+			if err := applyTag(property, tag, &required); err != nil {
+				panic(fmt.Sprintf("jsonschema: field %s.%s: %v", t.Name(), field.Name, err))
+			}
+		}
+
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyTag applies the keywords of a jsonschema struct tag to schema
+func applyTag(schema *Schema, tag string, required *bool) error {
+	for _, entry := range splitTag(tag) {
+		key, value, _ := strings.Cut(entry, "=")
+
+		var err error
+		switch strings.TrimSpace(key) {
+		case "":
+		case "title":
+			schema.Title = value
+		case "description":
+			schema.Description = value
+		case "format":
+			schema.Format = value
+		case "pattern":
+			schema.Pattern = value
+		case "enum":
+			schema.Enum = append(schema.Enum, parseValue(schema.Type, value))
+		case "default":
+			schema.Default = parseValue(schema.Type, value)
+		case "minimum":
+			schema.Minimum, err = parseFloat(value)
+		case "maximum":
+			schema.Maximum, err = parseFloat(value)
+		case "multipleOf":
+			schema.MultipleOf, err = parseFloat(value)
+		case "exclusiveMinimum":
+			schema.ExclusiveMinimum, err = parseBool(value)
+		case "exclusiveMaximum":
+			schema.ExclusiveMaximum, err = parseBool(value)
+		case "uniqueItems":
+			schema.UniqueItems, err = parseBool(value)
+		case "minLength":
+			schema.MinLength, err = parseInt(value)
+		case "maxLength":
+			schema.MaxLength, err = parseInt(value)
+		case "minItems":
+			schema.MinItems, err = parseInt(value)
+		case "maxItems":
+			schema.MaxItems, err = parseInt(value)
+		case "required":
+			var b *bool
+			b, err = parseBool(value)
+			if err == nil {
+				*required = *b
+			}
+		default:
+			err = fmt.Errorf("unknown jsonschema tag key %q", key)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitTag splits a jsonschema tag on commas that are not escaped with a backslash
+func splitTag(tag string) []string {
+	var (
+		entries []string
+		current strings.Builder
+	)
+
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			entries = append(entries, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+
+	return append(entries, current.String())
+}
+
+// parseValue converts an enum or default value from a tag to the schema's type
+func parseValue(schemaType, value string) interface{} {
+	switch schemaType {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// parseFloat parses a numeric tag value
+func parseFloat(value string) (*float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q: %w", value, err)
+	}
+	return &f, nil
+}
+
+// parseInt parses an integer tag value
+func parseInt(value string) (*int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid integer %q: %w", value, err)
+	}
+	return &i, nil
+}
+
+// parseBool parses a boolean tag value, an empty value means true
+func parseBool(value string) (*bool, error) {
+	if value == "" {
+		value = "true"
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid boolean %q: %w", value, err)
+	}
+	return &b, nil
+}
+
+// hasOption reports whether a comma separated json tag option list contains option
+func hasOption(opts, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// defName returns the key of a type's entry in $defs. Recursive types from different packages and
+// instantiations of a generic type can share a name, so later ones are numbered, e.g. node2.
+func (r *reflector) defName(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	// drop the type arguments of generic types, they hold package paths
+	base, _, _ := strings.Cut(t.Name(), "[")
+	name := base
+	for n := 2; r.nameTaken(name); n++ {
+		name = base + strconv.Itoa(n)
+	}
+
+	r.names[t] = name
+	return name
+}
+
+// nameTaken reports whether a $defs key has been given to a type
+func (r *reflector) nameTaken(name string) bool {
+	for _, taken := range r.names {
+		if taken == name {
+			return true
+		}
+	}
+	return false
+}
+
+// defRef returns the $ref pointing at a type's entry in $defs
+func (r *reflector) defRef(t reflect.Type) string {
+	return "#/$defs/" + r.defName(t)
+}
@@ -0,0 +1,151 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reflectEpisode struct {
+	Season  int `json:"season" jsonschema:"minimum=1"`
+	Episode int `json:"episode"`
+}
+
+type reflectQuote struct {
+	reflectEpisode
+
+	Quote      string            `json:"quote" jsonschema:"description=The quote\\, as captioned,minLength=1"`
+	Confidence float64           `json:"confidence" jsonschema:"minimum=0,maximum=1"`
+	Character  string            `json:"character,omitempty" jsonschema:"enum=Homer,enum=Bart"`
+	Tags       []string          `json:"tags,omitempty"`
+	Extra      map[string]int    `json:"extra,omitempty"`
+	AiredAt    *time.Time        `json:"aired_at,omitempty"`
+	Raw        json.RawMessage   `json:"raw,omitempty"`
+	Ignored    string            `json:"-"`
+	Replies    []*reflectComment `json:"replies,omitempty"`
+	unexported string
+}
+
+type reflectComment struct {
+	Text    string            `json:"text"`
+	Replies []*reflectComment `json:"replies,omitempty"`
+}
+
+type reflectNode[T any] struct {
+	Value    T                 `json:"value"`
+	Children []*reflectNode[T] `json:"children,omitempty"`
+}
+
+// TestReflect tests building a schema from a struct type
+func TestReflect(t *testing.T) {
+	schema := Reflect[[]reflectQuote]()
+
+	require.Equal(t, "array", schema.Type)
+	item := schema.Items
+	require.NotNil(t, item)
+	assert.Equal(t, "object", item.Type)
+	assert.Equal(t, false, item.AdditionalProperties, "Structs should not allow additional properties")
+	assert.ElementsMatch(t, []string{"season", "episode", "quote", "confidence"}, item.Required, "Fields without omitempty should be required")
+
+	assert.NotContains(t, item.Properties, "Ignored", "Skipped fields should not be included")
+	assert.NotContains(t, item.Properties, "unexported", "Unexported fields should not be included")
+
+	assert.Equal(t, "integer", item.Properties["season"].Type, "Embedded struct fields should be flattened")
+	assert.Equal(t, 1.0, *item.Properties["season"].Minimum)
+
+	quote := item.Properties["quote"]
+	assert.Equal(t, "The quote, as captioned", quote.Description, "Escaped commas should be kept")
+	assert.Equal(t, 1, *quote.MinLength)
+
+	assert.Equal(t, 0.0, *item.Properties["confidence"].Minimum)
+	assert.Equal(t, 1.0, *item.Properties["confidence"].Maximum)
+	assert.Equal(t, []interface{}{"Homer", "Bart"}, item.Properties["character"].Enum)
+	assert.Equal(t, "string", item.Properties["tags"].Items.Type)
+	assert.Equal(t, "integer", item.Properties["extra"].AdditionalProperties.(*Schema).Type)
+	assert.Equal(t, "date-time", item.Properties["aired_at"].Format)
+	assert.Equal(t, &Schema{}, item.Properties["raw"])
+
+	replies := item.Properties["replies"]
+	assert.Equal(t, "#/$defs/reflectComment", replies.Items.Ref, "Recursive types should be referenced")
+	require.Contains(t, schema.Defs, "reflectComment")
+	assert.Equal(t, "#/$defs/reflectComment", schema.Defs["reflectComment"].Properties["replies"].Items.Ref)
+}
+
+// TestReflectRecursiveRoot tests that a type referring to itself at the root uses a root reference
+func TestReflectRecursiveRoot(t *testing.T) {
+	schema := Reflect[reflectComment]()
+
+	assert.Equal(t, "object", schema.Type)
+	assert.Equal(t, "#", schema.Properties["replies"].Items.Ref)
+	assert.Empty(t, schema.Defs)
+}
+
+// TestReflectDefNames tests that recursive types sharing a name get their own $defs entries
+func TestReflectDefNames(t *testing.T) {
+	type thread struct {
+		Comments []*reflectComment `json:"comments"`
+	}
+
+	// shadows the package level type of the same name
+	type reflectComment struct {
+		Note    string            `json:"note"`
+		Replies []*reflectComment `json:"replies,omitempty"`
+	}
+
+	type forum struct {
+		Thread  thread                `json:"thread"`
+		Notes   []*reflectComment     `json:"notes"`
+		Ints    reflectNode[int]      `json:"ints"`
+		Strings []reflectNode[string] `json:"strings"`
+	}
+
+	schema := Reflect[forum]()
+	require.Len(t, schema.Defs, 4, "Each recursive type should have its own definition")
+
+	comments := schema.Properties["thread"].Properties["comments"].Items.Ref
+	notes := schema.Properties["notes"].Items.Ref
+	assert.NotEqual(t, comments, notes, "Same named types should not share a definition")
+	assert.Contains(t, schema.Defs[strings.TrimPrefix(comments, "#/$defs/")].Properties, "text")
+	assert.Contains(t, schema.Defs[strings.TrimPrefix(notes, "#/$defs/")].Properties, "note")
+
+	ints := schema.Properties["ints"].Ref
+	strs := schema.Properties["strings"].Items.Ref
+	assert.Equal(t, "#/$defs/reflectNode", ints, "Type arguments should be left out of the name")
+	assert.Equal(t, "#/$defs/reflectNode2", strs)
+	assert.Equal(t, "integer", schema.Defs["reflectNode"].Properties["value"].Type)
+	assert.Equal(t, "string", schema.Defs["reflectNode2"].Properties["value"].Type)
+	assert.Equal(t, strs, schema.Defs["reflectNode2"].Properties["children"].Items.Ref)
+
+	assert.NoError(t, schema.Validate(map[string]any{
+		"thread":  map[string]any{"comments": []any{map[string]any{"text": "d'oh"}}},
+		"notes":   []any{map[string]any{"note": "woo hoo", "replies": []any{map[string]any{"note": "ay caramba"}}}},
+		"ints":    map[string]any{"value": 1.0, "children": []any{map[string]any{"value": 2.0}}},
+		"strings": []any{map[string]any{"value": "a"}},
+	}), "The references should resolve to the right definitions")
+}
+
+// TestReflectDefNamesUnshared tests that types which aren't in $defs don't take or number a $defs name
+func TestReflectDefNamesUnshared(t *testing.T) {
+	type board struct {
+		Nodes reflectNode[int] `json:"nodes"`
+	}
+
+	// shares a name with the recursive generic type but is not recursive itself
+	type reflectNode struct {
+		Label string `json:"label"`
+	}
+
+	type page struct {
+		Plain reflectNode `json:"plain"`
+		Board board       `json:"board"`
+	}
+
+	schema := Reflect[page]()
+	assert.Equal(t, "object", schema.Properties["plain"].Type, "A type that isn't recursive should be inlined")
+	assert.Equal(t, "#/$defs/reflectNode", schema.Properties["board"].Properties["nodes"].Ref, "Only colliding definitions should be numbered")
+	assert.Len(t, schema.Defs, 1)
+}
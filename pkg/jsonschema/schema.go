@@ -15,6 +15,7 @@ type Schema struct {
 	OneOf                []*Schema          `json:"oneOf,omitempty"`
 	Not                  *Schema            `json:"not,omitempty"`
 	Definitions          map[string]*Schema `json:"definitions,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
 	Title                string             `json:"title,omitempty"`
 	Description          string             `json:"description,omitempty"`
 	Default              interface{}        `json:"default,omitempty"`
@@ -0,0 +1,508 @@
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// maxRefDepth bounds how many $refs can be followed without descending into the data,
+// protecting against schemas whose references form a cycle.
+const maxRefDepth = 32
+
+// patternCache holds compiled pattern and patternProperties expressions
+var patternCache sync.Map
+
+// ValidationError describes a single way data does not match a schema.
+// Path is a JSON pointer to the offending value, e.g. /2/confidence.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + e.Message
+}
+
+// ValidationErrors is every violation found by Validate
+type ValidationErrors []ValidationError
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Validate checks data against the schema and returns ValidationErrors listing every violation,
+// or nil if the data is valid. $refs are resolved against the Definitions and $defs of s.
+//
+// data may be a decoded JSON value, a json.RawMessage holding encoded JSON, or any Go value,
+// which is validated as it would be encoded by encoding/json.
+func (s *Schema) Validate(data any) error {
+	value, err := normalize(data)
+	if err != nil {
+		return err
+	}
+
+	v := &validator{root: s}
+	v.validate(s, value, "", 0)
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// normalize converts data into the generic values produced by decoding JSON into an interface{}
+func normalize(data any) (any, error) {
+	var raw []byte
+	switch d := data.(type) {
+	case nil, bool, float64, string, []any, map[string]any:
+		return d, nil
+	case json.RawMessage:
+		raw = d
+	default:
+		var err error
+		if raw, err = json.Marshal(d); err != nil {
+			return nil, fmt.Errorf("error encoding data for validation: %w", err)
+		}
+	}
+
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("error decoding data for validation: %w", err)
+	}
+	return value, nil
+}
+
+// validator collects the violations found while walking a value
+type validator struct {
+	root *Schema
+	errs ValidationErrors
+}
+
+// fail records a violation at path
+func (v *validator) fail(path, format string, args ...any) {
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// matches reports whether value is valid against schema without recording any violations
+func (v *validator) matches(schema *Schema, value any, path string, depth int) bool {
+	sub := &validator{root: v.root}
+	sub.validate(schema, value, path, depth)
+	return len(sub.errs) == 0
+}
+
+// validate checks value against schema, recording violations under path
+func (v *validator) validate(schema *Schema, value any, path string, depth int) {
+	if schema == nil {
+		return
+	}
+
+	if schema.Ref != "" {
+		if depth >= maxRefDepth {
+			v.fail(path, "too many nested $ref resolving %s", schema.Ref)
+			return
+		}
+
+		resolved, err := v.resolve(schema.Ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.validate(resolved, value, path, depth+1)
+		return
+	}
+
+	if schema.Type != "" && !hasType(value, schema.Type) {
+		v.fail(path, "must be %s but is %s", article(schema.Type), typeName(value))
+		return
+	}
+
+	if len(schema.Enum) > 0 && !slices.ContainsFunc(schema.Enum, func(e any) bool { return jsonEqual(e, value) }) {
+		v.fail(path, "must be one of %s", formatEnum(schema.Enum))
+	}
+
+	switch val := value.(type) {
+	case float64:
+		v.validateNumber(schema, val, path)
+	case string:
+		v.validateString(schema, val, path)
+	case []any:
+		v.validateArray(schema, val, path)
+	case map[string]any:
+		v.validateObject(schema, val, path)
+	}
+
+	for _, sub := range schema.AllOf {
+		v.validate(sub, value, path, depth)
+	}
+
+	if len(schema.AnyOf) > 0 && !slices.ContainsFunc(schema.AnyOf, func(sub *Schema) bool { return v.matches(sub, value, path, depth) }) {
+		v.fail(path, "must match at least one schema in anyOf")
+	}
+
+	if len(schema.OneOf) > 0 {
+		matched := 0
+		for _, sub := range schema.OneOf {
+			if v.matches(sub, value, path, depth) {
+				matched++
+			}
+		}
+		if matched != 1 {
+			v.fail(path, "must match exactly one schema in oneOf but matches %d", matched)
+		}
+	}
+
+	if schema.Not != nil && v.matches(schema.Not, value, path, depth) {
+		v.fail(path, "must not match the schema in not")
+	}
+}
+
+// validateNumber applies the numeric keywords
+func (v *validator) validateNumber(schema *Schema, val float64, path string) {
+	if schema.Minimum != nil {
+		if isTrue(schema.ExclusiveMinimum) && val <= *schema.Minimum {
+			v.fail(path, "must be > %s", formatNumber(*schema.Minimum))
+		} else if val < *schema.Minimum {
+			v.fail(path, "must be >= %s", formatNumber(*schema.Minimum))
+		}
+	}
+
+	if schema.Maximum != nil {
+		if isTrue(schema.ExclusiveMaximum) && val >= *schema.Maximum {
+			v.fail(path, "must be < %s", formatNumber(*schema.Maximum))
+		} else if val > *schema.Maximum {
+			v.fail(path, "must be <= %s", formatNumber(*schema.Maximum))
+		}
+	}
+
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		quotient := val / *schema.MultipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.fail(path, "must be a multiple of %s", formatNumber(*schema.MultipleOf))
+		}
+	}
+}
+
+// validateString applies the string keywords
+func (v *validator) validateString(schema *Schema, val string, path string) {
+	length := utf8.RuneCountInString(val)
+
+	if schema.MinLength != nil && length < *schema.MinLength {
+		v.fail(path, "must be at least %d characters long", *schema.MinLength)
+	}
+
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		v.fail(path, "must be at most %d characters long", *schema.MaxLength)
+	}
+
+	if schema.Pattern != "" {
+		re, err := compilePattern(schema.Pattern)
+		if err != nil {
+			v.fail(path, "%v", err)
+		} else if !re.MatchString(val) {
+			v.fail(path, "must match pattern %s", schema.Pattern)
+		}
+	}
+}
+
+// validateArray applies the array keywords and validates each item
+func (v *validator) validateArray(schema *Schema, val []any, path string) {
+	if schema.MinItems != nil && len(val) < *schema.MinItems {
+		v.fail(path, "must have at least %d items", *schema.MinItems)
+	}
+
+	if schema.MaxItems != nil && len(val) > *schema.MaxItems {
+		v.fail(path, "must have at most %d items", *schema.MaxItems)
+	}
+
+	if isTrue(schema.UniqueItems) {
+		for i := range val {
+			for j := 0; j < i; j++ {
+				if jsonEqual(val[i], val[j]) {
+					v.fail(itemPath(path, i), "must be unique but equals item %d", j)
+					break
+				}
+			}
+		}
+	}
+
+	if schema.Items != nil {
+		for i, item := range val {
+			v.validate(schema.Items, item, itemPath(path, i), 0)
+		}
+	}
+}
+
+// validateObject applies the object keywords and validates each property
+func (v *validator) validateObject(schema *Schema, val map[string]any, path string) {
+	for _, name := range schema.Required {
+		if _, ok := val[name]; !ok {
+			v.fail(path, "missing required property %q", name)
+		}
+	}
+
+	if schema.MinProperties != nil && len(val) < *schema.MinProperties {
+		v.fail(path, "must have at least %d properties", *schema.MinProperties)
+	}
+
+	if schema.MaxProperties != nil && len(val) > *schema.MaxProperties {
+		v.fail(path, "must have at most %d properties", *schema.MaxProperties)
+	}
+
+	additional, additionalAllowed := additionalSchema(schema.AdditionalProperties)
+
+	keys := make([]string, 0, len(val))
+	for name := range val {
+		keys = append(keys, name)
+	}
+	slices.Sort(keys)
+
+	for _, name := range keys {
+		propertyPath := propertyPath(path, name)
+		known := false
+
+		if property, ok := schema.Properties[name]; ok {
+			known = true
+			v.validate(property, val[name], propertyPath, 0)
+		}
+
+		for pattern, property := range schema.PatternProperties {
+			re, err := compilePattern(pattern)
+			if err != nil {
+				v.fail(propertyPath, "%v", err)
+				continue
+			}
+			if re.MatchString(name) {
+				known = true
+				v.validate(property, val[name], propertyPath, 0)
+			}
+		}
+
+		if known {
+			continue
+		}
+
+		if !additionalAllowed {
+			v.fail(propertyPath, "is not an allowed property")
+		} else if additional != nil {
+			v.validate(additional, val[name], propertyPath, 0)
+		}
+	}
+
+	for name, dependency := range schema.Dependencies {
+		if _, ok := val[name]; !ok {
+			continue
+		}
+
+		switch dep := dependency.(type) {
+		case []string:
+			v.requireAll(val, dep, path, name)
+		case []any:
+			names := make([]string, 0, len(dep))
+			for _, d := range dep {
+				if s, ok := d.(string); ok {
+					names = append(names, s)
+				}
+			}
+			v.requireAll(val, names, path, name)
+		default:
+			if sub := toSchema(dep); sub != nil {
+				v.validate(sub, val, path, 0)
+			}
+		}
+	}
+}
+
+// requireAll records a violation for each name missing from val because dependent is present
+func (v *validator) requireAll(val map[string]any, names []string, path, dependent string) {
+	for _, name := range names {
+		if _, ok := val[name]; !ok {
+			v.fail(path, "missing property %q required by %q", name, dependent)
+		}
+	}
+}
+
+// resolve finds the schema a local $ref points to
+func (v *validator) resolve(ref string) (*Schema, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+
+	for prefix, defs := range map[string]map[string]*Schema{
+		"#/definitions/": v.root.Definitions,
+		"#/$defs/":       v.root.Defs,
+	} {
+		if name, ok := strings.CutPrefix(ref, prefix); ok {
+			name = strings.ReplaceAll(strings.ReplaceAll(name, "~1", "/"), "~0", "~")
+			if schema, ok := defs[name]; ok {
+				return schema, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("cannot resolve $ref %s", ref)
+}
+
+// additionalSchema interprets additionalProperties, which is either a boolean or a schema.
+// It returns the schema additional properties must match and whether they are allowed at all.
+func additionalSchema(additional interface{}) (*Schema, bool) {
+	switch a := additional.(type) {
+	case nil:
+		return nil, true
+	case bool:
+		return nil, a
+	case *bool:
+		return nil, a == nil || *a
+	default:
+		return toSchema(a), true
+	}
+}
+
+// toSchema converts a value held in an interface{} keyword into a Schema
+func toSchema(value interface{}) *Schema {
+	switch s := value.(type) {
+	case *Schema:
+		return s
+	case Schema:
+		return &s
+	default:
+		// most likely a schema that was itself decoded from JSON
+		raw, err := json.Marshal(s)
+		if err != nil {
+			return nil
+		}
+		var schema Schema
+		if err := json.Unmarshal(raw, &schema); err != nil {
+			return nil
+		}
+		return &schema
+	}
+}
+
+// hasType reports whether a decoded JSON value is of the named JSON schema type
+func hasType(value any, schemaType string) bool {
+	switch schemaType {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	default:
+		return true
+	}
+}
+
+// typeName returns the JSON type of a decoded value for error messages
+func typeName(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "a boolean"
+	case float64:
+		return "a number"
+	case string:
+		return "a string"
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "an object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// article prefixes a JSON schema type name with "a" or "an"
+func article(schemaType string) string {
+	switch schemaType {
+	case "null":
+		return "null"
+	case "array", "object", "integer":
+		return "an " + schemaType
+	default:
+		return "a " + schemaType
+	}
+}
+
+// jsonEqual reports whether two values encode to the same JSON
+func jsonEqual(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// formatEnum renders enum values for error messages
+func formatEnum(enum []interface{}) string {
+	values := make([]string, len(enum))
+	for i, e := range enum {
+		b, _ := json.Marshal(e)
+		values[i] = string(b)
+	}
+	return strings.Join(values, ", ")
+}
+
+// formatNumber renders a limit without a trailing .0
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// isTrue reports whether an optional boolean keyword is set to true
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+// compilePattern compiles and caches a regular expression from a schema
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// itemPath appends an array index to a JSON pointer
+func itemPath(path string, index int) string {
+	return path + "/" + strconv.Itoa(index)
+}
+
+// propertyPath appends an escaped property name to a JSON pointer
+func propertyPath(path string, name string) string {
+	return path + "/" + strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,128 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validateQuote struct {
+	Quote      string  `json:"quote" jsonschema:"minLength=1"`
+	Confidence float64 `json:"confidence" jsonschema:"minimum=0,maximum=1"`
+	Character  string  `json:"character,omitempty" jsonschema:"enum=Homer,enum=Bart"`
+	Season     int     `json:"season,omitempty"`
+}
+
+// TestValidate tests validating decoded model output against a reflected schema
+func TestValidate(t *testing.T) {
+	schema := Reflect[[]validateQuote]()
+
+	tests := []struct {
+		name     string
+		data     string
+		expected []string
+	}{
+		{
+			name: "Valid",
+			data: `[{"quote":"d'oh","confidence":0.9,"character":"Homer","season":1}]`,
+		},
+		{
+			name:     "Out of range and missing",
+			data:     `[{"quote":"d'oh","confidence":0.9},{"confidence":0.5},{"quote":"ay caramba","confidence":7.3}]`,
+			expected: []string{`/1: missing required property "quote"`, `/2/confidence: must be <= 1`},
+		},
+		{
+			name:     "Wrong types",
+			data:     `[{"quote":"","confidence":"high","season":1.5,"character":"Lisa","extra":true}]`,
+			expected: []string{`/0/character: must be one of "Homer", "Bart"`, `/0/confidence: must be a number but is a string`, `/0/extra: is not an allowed property`, `/0/quote: must be at least 1 characters long`, `/0/season: must be an integer but is a number`},
+		},
+		{
+			name:     "Not an array",
+			data:     `{"quote":"d'oh"}`,
+			expected: []string{`/: must be an array but is an object`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(json.RawMessage(tt.data))
+			if len(tt.expected) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errs ValidationErrors
+			require.ErrorAs(t, err, &errs)
+
+			messages := make([]string, len(errs))
+			for i, e := range errs {
+				messages[i] = e.Error()
+			}
+			assert.Equal(t, tt.expected, messages)
+		})
+	}
+}
+
+// TestValidateComposition tests $ref resolution and the combining keywords
+func TestValidateComposition(t *testing.T) {
+	minimum := 10.0
+	schema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"episode": {Ref: "#/definitions/episode"},
+			"tree":    {Ref: "#/$defs/tree"},
+			"id":      {AnyOf: []*Schema{NewStringSchema(), NewIntegerSchema()}},
+			"size":    {OneOf: []*Schema{NewIntegerSchema(), NewNumberSchema()}},
+			"name":    {Not: &Schema{Enum: []interface{}{"Billy"}}},
+		},
+		Definitions: map[string]*Schema{
+			"episode": {Type: "string", Pattern: `^S\d{2}E\d{2}$`},
+		},
+		Defs: map[string]*Schema{
+			"tree": NewObjectSchema(map[string]*Schema{
+				"value":    {Type: "number", Minimum: &minimum},
+				"children": NewArraySchema(&Schema{Ref: "#/$defs/tree"}),
+			}, []string{"value"}),
+		},
+	}
+
+	valid := map[string]any{
+		"episode": "S16E01",
+		"tree":    map[string]any{"value": 10.0, "children": []any{map[string]any{"value": 11.0}}},
+		"id":      3.0,
+		"size":    1.5,
+		"name":    "Homer",
+	}
+	assert.NoError(t, schema.Validate(valid), "A document matching every composed schema should be valid")
+
+	noneOf := map[string]any{
+		"episode": "S16E01",
+		"tree":    map[string]any{"value": 10.0},
+		"id":      "abc",
+		"size":    "big",
+		"name":    "Homer",
+	}
+	err := schema.Validate(noneOf)
+	require.Error(t, err, "size matches neither oneOf schema")
+	assert.Equal(t, "/size: must match exactly one schema in oneOf but matches 0", err.Error())
+
+	invalid := map[string]any{
+		"episode": "16x01",
+		"tree":    map[string]any{"value": 10.0, "children": []any{map[string]any{"value": 1.0}}},
+		"id":      true,
+		"size":    1.0,
+		"name":    "Billy",
+	}
+	err = schema.Validate(invalid)
+	var errs ValidationErrors
+	require.ErrorAs(t, err, &errs)
+	assert.ElementsMatch(t, ValidationErrors{
+		{Path: "/episode", Message: `must match pattern ^S\d{2}E\d{2}$`},
+		{Path: "/id", Message: "must match at least one schema in anyOf"},
+		{Path: "/name", Message: "must not match the schema in not"},
+		{Path: "/size", Message: "must match exactly one schema in oneOf but matches 2"},
+		{Path: "/tree/children/0/value", Message: "must be >= 10"},
+	}, errs)
+}
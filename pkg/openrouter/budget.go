@@ -0,0 +1,380 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/filelock"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrBudgetExceeded is returned instead of making a call once a spending cap has been reached
+var ErrBudgetExceeded = errors.New("openrouter budget exceeded")
+
+const (
+	// DefaultDowngradeAt is the fraction of a cap after which the budget switches to the cheaper model
+	DefaultDowngradeAt = 0.8
+
+	// DefaultKeyCheckInterval is how often the key's remaining credit is checked
+	DefaultKeyCheckInterval = 5 * time.Minute
+)
+
+// Budget caps how much is spent through a Client per day and per month.
+// Spend is taken from the cost OpenRouter reports for each call and kept in a state file so the
+// caps hold across runs, e.g. when billy-bot answers webhooks unattended. Set it on Client.Budget.
+type Budget struct {
+	// DailyLimit is the most to spend in a UTC day in US dollars, 0 for no limit
+	DailyLimit float64
+	// MonthlyLimit is the most to spend in a UTC month in US dollars, 0 for no limit
+	MonthlyLimit float64
+	// DowngradeAt is the fraction of a limit after which calls are switched to FallbackModel
+	DowngradeAt float64
+	// FallbackModel is a cheaper model to use once DowngradeAt is reached, calls are refused at the limit either way
+	FallbackModel string
+	// MaxPrice is sent with every call so providers above it are not used, unless the call sets its own
+	MaxPrice *MaxPrice
+	// StatePath is the file the spend is kept in
+	StatePath string
+	// KeyCheckInterval is how often GET /key is used to check the key has credit left, 0 to never check
+	KeyCheckInterval time.Duration
+	// Models is where the catalog used to price the worst case of a call is cached
+	Models ModelCache
+
+	mu           sync.Mutex
+	lastKeyCheck time.Time
+	now          func() time.Time
+}
+
+// NewBudget creates a Budget with the default thresholds that keeps its state in the user's cache directory
+func NewBudget(dailyLimit, monthlyLimit float64) *Budget {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return &Budget{
+		DailyLimit:       dailyLimit,
+		MonthlyLimit:     monthlyLimit,
+		DowngradeAt:      DefaultDowngradeAt,
+		StatePath:        filepath.Join(dir, "billy-bot", "budget.json"),
+		KeyCheckInterval: DefaultKeyCheckInterval,
+		Models:           DefaultModelCache(),
+	}
+}
+
+// budgetState is the on disk format of the spend, keyed by UTC day (2006-01-02) and month (2006-01)
+type budgetState struct {
+	Days   map[string]float64 `json:"days"`
+	Months map[string]float64 `json:"months"`
+}
+
+// Spent returns how much has been spent today and this month
+func (b *Budget) Spent() (day, month float64, err error) {
+	state, unlock, err := b.lock()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer unlock()
+
+	day, month = b.spent(state)
+	return day, month, nil
+}
+
+// Add records spending cost, in US dollars, against today and this month.
+// The state file is locked while it is updated so concurrent runs don't lose each other's spend.
+func (b *Budget) Add(cost float64) error {
+	if cost <= 0 {
+		return nil
+	}
+
+	state, unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	now := b.clock()
+	day, month := now.Format(time.DateOnly), now.Format("2006-01")
+	state.Days[day] += cost
+	state.Months[month] += cost
+
+	// only today and this month are ever read, keep a little history for anyone looking at the file
+	for d := range state.Days {
+		if d < now.AddDate(0, 0, -31).Format(time.DateOnly) {
+			delete(state.Days, d)
+		}
+	}
+	for m := range state.Months {
+		if m < now.AddDate(-1, 0, 0).Format("2006-01") {
+			delete(state.Months, m)
+		}
+	}
+
+	return b.save(state)
+}
+
+// lock holds the budget's mutex and the state file's lock and reads the state, call unlock once done with it
+func (b *Budget) lock() (state budgetState, unlock func(), err error) {
+	b.mu.Lock()
+
+	unlockFile, err := filelock.Lock(b.StatePath)
+	if err != nil {
+		b.mu.Unlock()
+		return state, nil, fmt.Errorf("error locking budget state: %w", err)
+	}
+
+	unlock = func() {
+		if err := unlockFile(); err != nil {
+			log.Warn().Err(err).Str("path", b.StatePath).Msg("failed to unlock budget state")
+		}
+		b.mu.Unlock()
+	}
+
+	state, err = b.load()
+	if err != nil {
+		unlock()
+		return state, nil, err
+	}
+
+	return state, unlock, nil
+}
+
+// spent returns how much of state was spent today and this month
+func (b *Budget) spent(state budgetState) (day, month float64) {
+	now := b.clock()
+	return state.Days[now.Format(time.DateOnly)], state.Months[now.Format("2006-01")]
+}
+
+// guard checks the budget before a call of promptSize bytes. It refuses the call if its worst case cost
+// would take the spend past a limit or the key is out of credit, switches model to the fallback model if
+// a limit is nearly reached and sets the max price on the request.
+func (b *Budget) guard(ctx context.Context, client *Client, model *string, request *BaseRequest, promptSize int) error {
+	if b == nil {
+		return nil
+	}
+
+	if err := b.checkKey(ctx, client); err != nil {
+		return err
+	}
+
+	var catalog []Model
+	if b.DailyLimit > 0 || b.MonthlyLimit > 0 {
+		var err error
+		catalog, err = client.CachedModels(ctx, b.Models)
+		if err != nil {
+			// the spend so far is still checked so a missing catalog is not fatal
+			log.Warn().Err(err).Msg("unable to get the openrouter models to estimate the cost of the call")
+		}
+	}
+
+	// the spend is checked under the state file's lock so it is not read while another run records its spend
+	state, unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	day, month := b.spent(state)
+
+	limits := []struct {
+		name         string
+		spent, limit float64
+	}{
+		{"daily", day, b.DailyLimit},
+		{"monthly", month, b.MonthlyLimit},
+	}
+
+	for _, limit := range limits {
+		if limit.limit > 0 && b.FallbackModel != "" && limit.spent >= limit.limit*b.DowngradeAt && *model != b.FallbackModel {
+			log.Warn().Str("model", *model).Str("fallback_model", b.FallbackModel).Msg("nearing the openrouter budget, switching to the fallback model")
+			*model = b.FallbackModel
+			// the fallbacks could be as expensive as the original model
+			request.Models = nil
+			break
+		}
+	}
+
+	estimate := 0.0
+	for _, id := range append([]string{*model}, request.Models...) {
+		if m, ok := FindModel(catalog, id); ok {
+			estimate = max(estimate, worstCaseCost(m, request, promptSize))
+		}
+	}
+
+	for _, limit := range limits {
+		if limit.limit <= 0 {
+			continue
+		}
+
+		if limit.spent >= limit.limit || limit.spent+estimate > limit.limit {
+			return fmt.Errorf("%w: spent $%.4f of the $%.2f %s limit and the call could cost up to $%.4f", ErrBudgetExceeded, limit.spent, limit.limit, limit.name, estimate)
+		}
+	}
+
+	if b.MaxPrice != nil {
+		if request.Provider == nil {
+			request.Provider = &ProviderRequest{}
+		}
+		if request.Provider.MaxPrice == nil {
+			request.Provider.MaxPrice = b.MaxPrice
+		}
+	}
+
+	return nil
+}
+
+// worstCaseCost is the most a call of promptSize bytes to model can cost in US dollars. No token is shorter
+// than a byte so the prompt is priced per byte, and the completion is priced at the most tokens it may use.
+// Models not in the catalog, e.g. the auto router, can't be priced and are estimated at 0.
+func worstCaseCost(model Model, request *BaseRequest, promptSize int) float64 {
+	completionTokens := model.ContextLength
+	if model.TopProvider.MaxCompletionTokens != nil {
+		completionTokens = *model.TopProvider.MaxCompletionTokens
+	}
+	if request.MaxTokens != nil {
+		completionTokens = *request.MaxTokens
+	}
+
+	return parsePrice(model.Pricing.Request) +
+		float64(promptSize)*model.Pricing.PromptPrice() +
+		float64(completionTokens)*model.Pricing.CompletionPrice()
+}
+
+// encodedSize is the size of request once encoded, an upper bound on the prompt tokens it uses
+func encodedSize(request any) int {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
+
+// checkKey refuses calls when the key has reached its credit limit, checking at most once per KeyCheckInterval
+func (b *Budget) checkKey(ctx context.Context, client *Client) error {
+	if b.KeyCheckInterval <= 0 {
+		return nil
+	}
+
+	// the attempt is recorded up front so a failing /key endpoint is not hit on every call
+	b.mu.Lock()
+	now := b.clock()
+	due := now.Sub(b.lastKeyCheck) >= b.KeyCheckInterval
+	if due {
+		b.lastKeyCheck = now
+	}
+	b.mu.Unlock()
+	if !due {
+		return nil
+	}
+
+	key, err := client.GetKey(ctx)
+	if err != nil {
+		// the spend caps still apply so a failed check is not fatal
+		log.Warn().Err(err).Msg("unable to check the openrouter key's credit")
+		return nil
+	}
+
+	if key.LimitRemaining != nil && *key.LimitRemaining <= 0 {
+		return fmt.Errorf("%w: the key has no credit remaining", ErrBudgetExceeded)
+	}
+
+	return nil
+}
+
+// record adds the cost of a call to the budget
+func (b *Budget) record(record UsageRecord) {
+	if b == nil {
+		return
+	}
+
+	if err := b.Add(record.Usage.Cost); err != nil {
+		log.Warn().Err(err).Str("path", b.StatePath).Msg("failed to record openrouter spend")
+	}
+}
+
+// clock returns the current time in UTC
+func (b *Budget) clock() time.Time {
+	if b.now != nil {
+		return b.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// load reads the state file, a missing file is an empty state
+func (b *Budget) load() (budgetState, error) {
+	state := budgetState{Days: map[string]float64{}, Months: map[string]float64{}}
+
+	data, err := os.ReadFile(b.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("error reading budget state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("error parsing budget state %s: %w", b.StatePath, err)
+	}
+	if state.Days == nil {
+		state.Days = map[string]float64{}
+	}
+	if state.Months == nil {
+		state.Months = map[string]float64{}
+	}
+
+	return state, nil
+}
+
+// save writes the state file, replacing it atomically so a crash does not lose the spend
+func (b *Budget) save(state budgetState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding budget state: %w", err)
+	}
+
+	if err := filelock.WriteFile(b.StatePath, data, 0o644); err != nil {
+		return fmt.Errorf("error writing budget state: %w", err)
+	}
+
+	return nil
+}
+
+// Key describes the API key's usage and credit limit.
+// Based on https://openrouter.ai/docs/api-reference/limits as of 2025-06-09.
+type Key struct {
+	Label string `json:"label,omitempty"`
+	// Usage is the number of credits used
+	Usage float64 `json:"usage"`
+	// Limit is the credit limit of the key, nil for unlimited
+	Limit *float64 `json:"limit"`
+	// LimitRemaining is the credit left before the limit, nil for unlimited
+	LimitRemaining *float64      `json:"limit_remaining"`
+	IsFreeTier     bool          `json:"is_free_tier"`
+	RateLimit      *KeyRateLimit `json:"rate_limit,omitempty"`
+}
+
+// KeyRateLimit is the request rate the key is allowed
+type KeyRateLimit struct {
+	Requests int    `json:"requests"`
+	Interval string `json:"interval"`
+}
+
+// keyResponse is the response from the key endpoint
+type keyResponse struct {
+	Data Key `json:"data"`
+}
+
+// GetKey fetches the usage and credit limit of the client's API key
+func (c *Client) GetKey(ctx context.Context) (Key, error) {
+	result := Get[keyResponse](ctx, c, "key")
+	if result.Err != nil {
+		return Key{}, fmt.Errorf("error getting key: %w", result.Err)
+	}
+	return result.Result.Data, nil
+}
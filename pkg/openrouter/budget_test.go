@@ -0,0 +1,192 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBudget tests that spend is recorded, the model is downgraded near the limit and calls are refused at it
+func TestBudget(t *testing.T) {
+	var models []string
+	var maxPrices []*MaxPrice
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/key":
+			fmt.Fprint(w, `{"data":{"label":"test","usage":1.5,"limit":10,"limit_remaining":8.5}}`)
+			return
+		case "/models":
+			fmt.Fprint(w, `{"data":[{"id":"expensive/model","pricing":{"prompt":"0.000001","completion":"0.00001"},"top_provider":{"max_completion_tokens":1000}}]}`)
+			return
+		}
+
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		models = append(models, request.Model)
+		maxPrices = append(maxPrices, request.Provider.MaxPrice)
+
+		fmt.Fprintf(w, `{"id":"gen-%d","choices":[{"message":{"role":"assistant","content":"d'oh"}}],"usage":{"total_tokens":10,"cost":0.45}}`, len(models))
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 6, 9, 12, 0, 0, 0, time.UTC)
+	prompt := 1.0
+
+	budget := NewBudget(1, 0)
+	budget.StatePath = filepath.Join(t.TempDir(), "budget.json")
+	budget.FallbackModel = "cheap/model"
+	budget.MaxPrice = &MaxPrice{Prompt: &prompt}
+	budget.Models.Path = filepath.Join(t.TempDir(), "models.json")
+	budget.now = func() time.Time { return now }
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Model = "expensive/model"
+	client.Budget = budget
+
+	request := ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+
+	for range 3 {
+		require.NoError(t, client.ChatCompletion(context.Background(), request).Err)
+	}
+	assert.Equal(t, []string{"expensive/model", "expensive/model", "cheap/model"}, models, "The model should be downgraded past 80% of the limit")
+	require.NotNil(t, maxPrices[0], "The max price should be sent")
+	assert.Equal(t, &prompt, maxPrices[0].Prompt)
+
+	day, month, err := budget.Spent()
+	require.NoError(t, err)
+	assert.InDelta(t, 1.35, day, 1e-9)
+	assert.InDelta(t, 1.35, month, 1e-9)
+
+	result := client.ChatCompletion(context.Background(), request)
+	require.ErrorIs(t, result.Err, ErrBudgetExceeded, "Calls should be refused at the limit")
+	assert.Len(t, models, 3, "A refused call should not be sent")
+
+	now = now.AddDate(0, 0, 1)
+	require.NoError(t, client.ChatCompletion(context.Background(), request).Err, "The daily limit should reset the next day")
+	assert.Equal(t, "expensive/model", models[3])
+}
+
+// TestBudgetEstimate tests that calls are refused when their worst case cost would take the spend past a limit
+func TestBudgetEstimate(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/models" {
+			fmt.Fprint(w, `{"data":[{"id":"test/model","pricing":{"prompt":"0","completion":"0.001"},"top_provider":{"max_completion_tokens":2000}}]}`)
+			return
+		}
+		calls++
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"d'oh"}}],"usage":{"cost":0.1}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Model = "test/model"
+	client.Budget = NewBudget(1, 0)
+	client.Budget.KeyCheckInterval = 0
+	client.Budget.StatePath = filepath.Join(t.TempDir(), "budget.json")
+	client.Budget.Models.Path = filepath.Join(t.TempDir(), "models.json")
+
+	request := ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+	result := client.ChatCompletion(context.Background(), request)
+	require.ErrorIs(t, result.Err, ErrBudgetExceeded, "2000 completion tokens could cost $2, more than the limit")
+	assert.Zero(t, calls, "The completion should not be sent")
+
+	maxTokens := 500
+	request.MaxTokens = &maxTokens
+	require.NoError(t, client.ChatCompletion(context.Background(), request).Err, "500 completion tokens cost at most $0.50")
+
+	maxTokens = 950
+	result = client.ChatCompletion(context.Background(), request)
+	require.ErrorIs(t, result.Err, ErrBudgetExceeded, "$0.95 on top of the $0.10 spent could pass the limit")
+	assert.Equal(t, 1, calls)
+}
+
+// TestBudgetKeyExhausted tests that calls are refused when the key has no credit left
+func TestBudgetKeyExhausted(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/key" {
+			fmt.Fprint(w, `{"data":{"usage":10,"limit":10,"limit_remaining":0}}`)
+			return
+		}
+		calls++
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Budget = NewBudget(0, 0)
+	client.Budget.StatePath = filepath.Join(t.TempDir(), "budget.json")
+
+	result := client.ChatCompletion(context.Background(), ChatCompletionRequest{})
+	require.ErrorIs(t, result.Err, ErrBudgetExceeded)
+	assert.Zero(t, calls, "The completion should not be sent")
+}
+
+// TestBudgetSharedState tests that budgets in different processes sharing a state file don't lose spend
+func TestBudgetSharedState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget.json")
+
+	// separate budgets have separate mutexes, like separate processes
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			budget := NewBudget(0, 0)
+			budget.StatePath = path
+			assert.NoError(t, budget.Add(0.1))
+		}()
+	}
+	wg.Wait()
+
+	budget := NewBudget(0, 0)
+	budget.StatePath = path
+	day, month, err := budget.Spent()
+	require.NoError(t, err)
+	assert.InDelta(t, 1.0, day, 1e-9, "Every run's spend should be kept")
+	assert.InDelta(t, 1.0, month, 1e-9)
+}
+
+// TestBudgetKeyCheckFailure tests that a failing key check is not retried on every call
+func TestBudgetKeyCheckFailure(t *testing.T) {
+	keyChecks := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/key" {
+			keyChecks++
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"d'oh"}}]}`)
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 6, 9, 12, 0, 0, 0, time.UTC)
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Retry = NoRetry()
+	client.Budget = NewBudget(0, 0)
+	client.Budget.StatePath = filepath.Join(t.TempDir(), "budget.json")
+	client.Budget.now = func() time.Time { return now }
+
+	request := ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+	for range 3 {
+		require.NoError(t, client.ChatCompletion(context.Background(), request).Err, "A failed key check should not fail the call")
+	}
+	assert.Equal(t, 1, keyChecks, "The key should be checked once per interval even when the check fails")
+
+	now = now.Add(DefaultKeyCheckInterval)
+	require.NoError(t, client.ChatCompletion(context.Background(), request).Err)
+	assert.Equal(t, 2, keyChecks, "The key should be checked again after the interval")
+}
@@ -0,0 +1,185 @@
+package openrouter
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the OpenRouter API
+	DefaultBaseURL = "https://openrouter.ai/api/v1/"
+
+	// DefaultModel is the model used when neither the request nor the client names one
+	DefaultModel = "openrouter/auto"
+)
+
+// Client holds the configuration needed to talk to OpenRouter or any OpenAI compatible gateway.
+// The zero value is not usable, create one with NewClient.
+type Client struct {
+	// APIKey is sent as a bearer token on every request
+	APIKey string
+	// BaseURL is the API root that endpoints are resolved against, e.g. https://openrouter.ai/api/v1/
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient is used if nil
+	HTTPClient *http.Client
+	// Headers are added to every request, by default HTTP-Referer and X-Title
+	Headers http.Header
+	// Model is used for requests that do not name a model
+	Model string
+	// Retry controls how failed requests are retried
+	Retry RetryPolicy
+	// Usage records the usage of every completion if set
+	Usage *UsageTracker
+	// Budget caps the spend on completions if set
+	Budget *Budget
+}
+
+// NewClient creates a Client for the public OpenRouter API with the default headers and model.
+func NewClient(apiKey string) *Client {
+	headers := http.Header{}
+	headers.Set("HTTP-Referer", "https://github.com/kklipsch/billy-bot")
+	headers.Set("X-Title", "Billy Bot")
+
+	return &Client{
+		APIKey:     apiKey,
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: http.DefaultClient,
+		Headers:    headers,
+		Model:      DefaultModel,
+		Retry:      DefaultRetryPolicy(),
+	}
+}
+
+// URL resolves an endpoint such as "chat/completions" against the client's base URL
+func (c *Client) URL(endpoint string) string {
+	baseURL := c.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(endpoint, "/")
+}
+
+// Do sends an HTTP request with the client's headers and credentials applied,
+// retrying transient failures according to the client's retry policy.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	c.AddHeaders(req)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return c.Retry.do(httpClient, req)
+}
+
+// AddHeaders adds the content type, authorization and default headers to an HTTP request.
+func (c *Client) AddHeaders(req *http.Request) {
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	for key, values := range c.Headers {
+		for i, value := range values {
+			if i == 0 {
+				req.Header.Set(key, value)
+			} else {
+				req.Header.Add(key, value)
+			}
+		}
+	}
+}
+
+// ChatCompletion sends a request to the chat completions endpoint.
+// If the request does not name a model the client's default model is used.
+func (c *Client) ChatCompletion(ctx context.Context, request ChatCompletionRequest) Response[ChatCompletionResponse] {
+	if request.Model == "" {
+		request.Model = c.Model
+	}
+	includeUsage(&request.BaseRequest)
+	if err := c.Budget.guard(ctx, c, &request.Model, &request.BaseRequest, encodedSize(request)); err != nil {
+		return Response[ChatCompletionResponse]{Err: err}
+	}
+
+	req, err := c.NewChatCompletionReq(ctx, request)
+	result := Call[ChatCompletionResponse](ctx, c, req, err, http.StatusOK)
+	if result.Err == nil {
+		c.recordUsage(result.Result)
+	}
+	return result
+}
+
+// StreamChatCompletion sends a streaming request to the chat completions endpoint.
+// If the request does not name a model the client's default model is used.
+func (c *Client) StreamChatCompletion(ctx context.Context, request ChatCompletionRequest) (<-chan StreamEvent[ChatCompletionResponse], error) {
+	if request.Model == "" {
+		request.Model = c.Model
+	}
+	request.Stream = true
+	includeUsage(&request.BaseRequest)
+	if err := c.Budget.guard(ctx, c, &request.Model, &request.BaseRequest, encodedSize(request)); err != nil {
+		return nil, err
+	}
+
+	req, err := c.NewChatCompletionReq(ctx, request)
+	events, err := CallStream[ChatCompletionResponse](ctx, c, req, err)
+	if err != nil {
+		return nil, err
+	}
+	return trackStream(ctx, c, events), nil
+}
+
+// Completion sends a request to the legacy text completions endpoint.
+// If the request does not name a model the client's default model is used.
+func (c *Client) Completion(ctx context.Context, request CompletionRequest) Response[CompletionResponse] {
+	if request.Model == "" {
+		request.Model = c.Model
+	}
+	includeUsage(&request.BaseRequest)
+	if err := c.Budget.guard(ctx, c, &request.Model, &request.BaseRequest, encodedSize(request)); err != nil {
+		return Response[CompletionResponse]{Err: err}
+	}
+
+	req, err := c.NewCompletionReq(ctx, request)
+	result := Call[CompletionResponse](ctx, c, req, err, http.StatusOK)
+	if result.Err == nil {
+		c.recordUsage(result.Result)
+	}
+	return result
+}
+
+// StreamCompletion sends a streaming request to the legacy text completions endpoint.
+// If the request does not name a model the client's default model is used.
+func (c *Client) StreamCompletion(ctx context.Context, request CompletionRequest) (<-chan StreamEvent[CompletionResponse], error) {
+	if request.Model == "" {
+		request.Model = c.Model
+	}
+	request.Stream = true
+	includeUsage(&request.BaseRequest)
+	if err := c.Budget.guard(ctx, c, &request.Model, &request.BaseRequest, encodedSize(request)); err != nil {
+		return nil, err
+	}
+
+	req, err := c.NewCompletionReq(ctx, request)
+	events, err := CallStream[CompletionResponse](ctx, c, req, err)
+	if err != nil {
+		return nil, err
+	}
+	return trackStream(ctx, c, events), nil
+}
+
+// includeUsage asks OpenRouter to report the token details and cost of the call unless the request says otherwise
+func includeUsage(request *BaseRequest) {
+	if request.Usage == nil {
+		request.Usage = &UsageRequest{Include: true}
+	}
+}
+
+// Get sends a GET request to any other OpenRouter endpoint, e.g. "models" or "key",
+// and decodes the response into T.
+func Get[T any](ctx context.Context, c *Client, endpoint string) Response[T] {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(endpoint), nil)
+	return Call[T](ctx, c, req, err, http.StatusOK)
+}
@@ -0,0 +1,121 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientChatCompletion tests that the client sends requests to its base URL with its headers and default model
+func TestClientChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path, "Endpoint should be resolved against the base URL")
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		assert.Equal(t, "Billy Bot", r.Header.Get("X-Title"))
+
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(t, "test/model", request.Model, "The client's default model should be used")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"gen-1","choices":[{"message":{"role":"assistant","content":"d'oh"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL + "/v1/"
+	client.HTTPClient = server.Client()
+	client.Model = "test/model"
+
+	result := client.ChatCompletion(context.Background(), ChatCompletionRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "hi"}},
+	})
+	require.NoError(t, result.Err, "ChatCompletion should not return an error")
+	require.Len(t, result.Result.Choices, 1)
+	assert.Equal(t, "d'oh", result.Result.Choices[0].Message.Content)
+}
+
+// TestClientRetry tests that transient failures are retried and that exhausted retries report every attempt
+func TestClientRetry(t *testing.T) {
+	attempts, failures := 0, 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request), "The body should be replayed on every attempt")
+
+		if attempts <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"code":429,"message":"slow down"}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"gen-1"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Retry.InitialBackoff = time.Millisecond
+
+	result := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "test/model"})
+	require.NoError(t, result.Err, "The request should succeed after retrying")
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, "gen-1", result.Result.ID)
+
+	attempts, failures = 0, 100
+	result = client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "test/model"})
+	require.Error(t, result.Err, "The request should fail once the attempts are used up")
+	assert.Equal(t, 3, attempts, "MaxAttempts requests should be made")
+
+	var retryErr *RetryError
+	require.ErrorAs(t, result.Err, &retryErr)
+	assert.Len(t, retryErr.Attempts, 3, "Every attempt's cause should be kept")
+	assert.Contains(t, result.Err.Error(), "slow down")
+}
+
+// TestClientRetryBrokenConnection tests that a connection broken mid request is only retried for idempotent methods
+func TestClientRetryBrokenConnection(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		conn, _, err := w.(http.Hijacker).Hijack()
+		require.NoError(t, err)
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Retry.InitialBackoff = time.Millisecond
+
+	result := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "test/model"})
+	require.Error(t, result.Err)
+	assert.Equal(t, 1, attempts, "A completion may already be running so it should not be sent again")
+
+	attempts = 0
+	_, err := client.ListModels(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 3, attempts, "Listing models is safe to repeat")
+}
+
+// TestClientRetryRefused tests that a connection that was never made is retried whatever the method
+func TestClientRetryRefused(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Retry.InitialBackoff = time.Millisecond
+
+	result := client.ChatCompletion(context.Background(), ChatCompletionRequest{Model: "test/model"})
+	var retryErr *RetryError
+	require.ErrorAs(t, result.Err, &retryErr)
+	assert.Len(t, retryErr.Attempts, 3, "A refused connection never delivered the request")
+}
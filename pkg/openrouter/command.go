@@ -0,0 +1,313 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/kklipsch/billy-bot/pkg/config"
+	"github.com/rs/zerolog/log"
+)
+
+// ClientFlags are the command line flags shared by every command that talks to OpenRouter.
+// Embed them in a command with `embed:""` and build the client with NewClient.
+type ClientFlags struct {
+	APIKey      string `name:"api-key" short:"k" help:"OpenRouter API key. If not provided, OPENROUTER_API_KEY env var is used."`
+	BaseURL     string `name:"openrouter-url" help:"Base URL of an OpenRouter compatible API. If not provided, OPENROUTER_BASE_URL env var is used, then the public OpenRouter API."`
+	MaxAttempts int    `name:"max-attempts" default:"3" help:"Maximum attempts for OpenRouter calls that fail with transient errors."`
+
+	DailyBudget        string   `name:"daily-budget" help:"Most to spend on OpenRouter per UTC day in US dollars. If not provided, BILLY_BOT_DAILY_BUDGET env var is used, then no limit."`
+	MonthlyBudget      string   `name:"monthly-budget" help:"Most to spend on OpenRouter per UTC month in US dollars. If not provided, BILLY_BOT_MONTHLY_BUDGET env var is used, then no limit."`
+	BudgetModel        string   `name:"budget-model" help:"Cheaper model to switch to when nearing a budget. If not provided, BILLY_BOT_BUDGET_MODEL env var is used."`
+	MaxPromptPrice     *float64 `name:"max-prompt-price" help:"Don't use providers charging more than this many US dollars per million prompt tokens."`
+	MaxCompletionPrice *float64 `name:"max-completion-price" help:"Don't use providers charging more than this many US dollars per million completion tokens."`
+}
+
+// NewClient creates a Client configured from the flags and environment
+func (f ClientFlags) NewClient() (*Client, error) {
+	return f.newClient(true)
+}
+
+// newClient creates a Client, optionally without an API key for the endpoints that do not need one
+func (f ClientFlags) newClient(requireKey bool) (*Client, error) {
+	apiKey, err := config.GetFlagOrEnvVar(f.APIKey, "OPENROUTER_API_KEY")
+	if err != nil && requireKey {
+		return nil, err
+	}
+
+	client := NewClient(apiKey)
+	if baseURL, err := config.GetFlagOrEnvVar(f.BaseURL, "OPENROUTER_BASE_URL"); err == nil {
+		client.BaseURL = baseURL
+	}
+	client.Retry.MaxAttempts = f.MaxAttempts
+
+	client.Budget, err = f.newBudget()
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// newBudget creates the Budget configured by the flags and environment, nil if nothing is capped
+func (f ClientFlags) newBudget() (*Budget, error) {
+	daily, err := budgetLimit(f.DailyBudget, "BILLY_BOT_DAILY_BUDGET")
+	if err != nil {
+		return nil, err
+	}
+
+	monthly, err := budgetLimit(f.MonthlyBudget, "BILLY_BOT_MONTHLY_BUDGET")
+	if err != nil {
+		return nil, err
+	}
+
+	var maxPrice *MaxPrice
+	if f.MaxPromptPrice != nil || f.MaxCompletionPrice != nil {
+		maxPrice = &MaxPrice{Prompt: f.MaxPromptPrice, Completion: f.MaxCompletionPrice}
+	}
+
+	if daily == 0 && monthly == 0 && maxPrice == nil {
+		return nil, nil
+	}
+
+	budget := NewBudget(daily, monthly)
+	budget.MaxPrice = maxPrice
+	budget.FallbackModel, _ = config.GetFlagOrEnvVar(f.BudgetModel, "BILLY_BOT_BUDGET_MODEL")
+
+	return budget, nil
+}
+
+// budgetLimit parses a budget from a flag or environment variable, 0 if neither is set
+func budgetLimit(flag, key string) (float64, error) {
+	value, err := config.GetFlagOrEnvVar(flag, key)
+	if err != nil {
+		return 0, nil
+	}
+
+	limit, err := strconv.ParseFloat(value, 64)
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid budget %q for %s, expected US dollars such as 1.50", value, key)
+	}
+
+	return limit, nil
+}
+
+// UsageFlags are the command line flags for reporting the usage of a command run.
+// Embed them in a command with `embed:""`, call Track on the client before use and Report when done.
+type UsageFlags struct {
+	Usage            string `enum:"text,json,none" default:"text" help:"How to report token usage and cost to stderr (text, json or none)."`
+	LookupGeneration bool   `name:"lookup-generation" help:"Fetch the native token counts and cost of each call from OpenRouter before reporting usage."`
+}
+
+// Track starts recording the usage of the client's calls
+func (f UsageFlags) Track(client *Client) {
+	client.Usage = NewUsageTracker()
+}
+
+// Report writes the usage recorded by the client to stderr in the requested format.
+// Failing to report is logged rather than returned so it does not hide the command's result.
+func (f UsageFlags) Report(ctx context.Context, client *Client) {
+	if client.Usage == nil || f.Usage == "none" {
+		return
+	}
+
+	if f.LookupGeneration {
+		client.Usage.LookupGenerations(ctx, client)
+	}
+
+	summary := client.Usage.Summary()
+
+	var err error
+	if f.Usage == "json" {
+		err = json.NewEncoder(os.Stderr).Encode(summary)
+	} else {
+		err = summary.WriteText(os.Stderr)
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("failed to report usage")
+	}
+}
+
+// Command represents the CLI command group for talking to OpenRouter directly
+type Command struct {
+	Complete CompleteCommand `cmd:"complete" help:"Send a raw prompt to the text completions endpoint."`
+}
+
+// CompleteCommand represents the complete subcommand for sending a prompt to a completion model
+type CompleteCommand struct {
+	ClientFlags `embed:""`
+	UsageFlags  `embed:""`
+
+	Prompt      string   `arg:"" help:"The prompt to complete."`
+	Model       string   `default:"openrouter/auto" help:"The model to use."`
+	MaxTokens   *int     `name:"max-tokens" help:"Maximum number of tokens to generate."`
+	Temperature *float64 `help:"Sampling temperature."`
+	Stream      bool     `help:"Print the completion as it is generated."`
+	Chat        bool     `help:"Send the prompt as a user message to the chat completions endpoint instead, to compare against chat models."`
+}
+
+// Run executes the complete command
+func (c *CompleteCommand) Run(ctx context.Context) error {
+	client, err := c.NewClient()
+	if err != nil {
+		return err
+	}
+
+	c.Track(client)
+	defer c.Report(ctx, client)
+
+	base := BaseRequest{
+		MaxTokens:   c.MaxTokens,
+		Temperature: c.Temperature,
+	}
+
+	if c.Chat {
+		return c.runChat(ctx, client, base)
+	}
+
+	request := CompletionRequest{BaseRequest: base, Model: c.Model, Prompt: c.Prompt}
+
+	var result CompletionResponse
+	if c.Stream {
+		events, err := client.StreamCompletion(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		result, err = CollectCompletionStream(events, func(chunk CompletionResponse) {
+			for _, choice := range chunk.Choices {
+				fmt.Print(choice.Text)
+			}
+		})
+		fmt.Println()
+		if err != nil {
+			return err
+		}
+	} else {
+		response := client.Completion(ctx, request)
+		if response.Err != nil {
+			return response.Err
+		}
+
+		result = response.Result
+		for _, choice := range result.Choices {
+			fmt.Println(choice.Text)
+		}
+	}
+
+	printSummary(result.Model, result.Provider)
+	return nil
+}
+
+// runChat sends the prompt to the chat completions endpoint
+func (c *CompleteCommand) runChat(ctx context.Context, client *Client, base BaseRequest) error {
+	request := ChatCompletionRequest{
+		BaseRequest: base,
+		Model:       c.Model,
+		Messages:    []ChatMessage{{Role: "user", Content: c.Prompt}},
+	}
+
+	var result ChatCompletionResponse
+	if c.Stream {
+		events, err := client.StreamChatCompletion(ctx, request)
+		if err != nil {
+			return err
+		}
+
+		result, err = CollectStream(events, func(chunk ChatCompletionResponse) {
+			for _, choice := range chunk.Choices {
+				if choice.Delta != nil {
+					fmt.Print(choice.Delta.Content)
+				}
+			}
+		})
+		fmt.Println()
+		if err != nil {
+			return err
+		}
+	} else {
+		response := client.ChatCompletion(ctx, request)
+		if response.Err != nil {
+			return response.Err
+		}
+
+		result = response.Result
+		for _, choice := range result.Choices {
+			if choice.Message != nil {
+				fmt.Println(choice.Message.Content)
+			}
+		}
+	}
+
+	printSummary(result.Model, result.Provider)
+	return nil
+}
+
+// printSummary writes which model answered to stderr
+func printSummary(model, provider string) {
+	fmt.Fprintf(os.Stderr, "model: %s (provider: %s)\n", model, provider)
+}
+
+// ModelsCommand represents the CLI command group for the OpenRouter model catalog
+type ModelsCommand struct {
+	List ModelsListCommand `cmd:"list" help:"List the models available through OpenRouter."`
+}
+
+// ModelsListCommand represents the list subcommand for printing the model catalog
+type ModelsListCommand struct {
+	ClientFlags `embed:""`
+
+	Supports []string `help:"Only list models that support all of these parameters, e.g. tools or structured_outputs."`
+	Refresh  bool     `help:"Fetch the catalog even if the cached copy is still fresh."`
+}
+
+// Run executes the models list command
+func (c *ModelsListCommand) Run(ctx context.Context) error {
+	client, err := c.newClient(false)
+	if err != nil {
+		return err
+	}
+
+	cache := DefaultModelCache()
+	if c.Refresh {
+		cache.TTL = 0
+	}
+
+	models, err := client.CachedModels(ctx, cache)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tCONTEXT\tPROMPT $/M\tCOMPLETION $/M\tMODALITY")
+
+	for _, model := range models {
+		if !supportsAll(model, c.Supports) {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%.2f\t%.2f\t%s\n",
+			model.ID,
+			model.ContextLength,
+			model.Pricing.PromptPrice()*1_000_000,
+			model.Pricing.CompletionPrice()*1_000_000,
+			model.Architecture.Modality,
+		)
+	}
+
+	return w.Flush()
+}
+
+// supportsAll reports whether model supports every parameter in params
+func supportsAll(model Model, params []string) bool {
+	for _, param := range params {
+		if !model.Supports(strings.TrimSpace(param)) {
+			return false
+		}
+	}
+	return true
+}
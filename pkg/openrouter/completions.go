@@ -15,26 +15,28 @@ type CompletionRequest struct {
 }
 
 // NewCompletionReq creates a new HTTP request for the OpenRouter completions API.
-// It takes a context and a ChatCompletionRequest and returns an HTTP request ready to be sent.
-func NewCompletionReq(ctx context.Context, request ChatCompletionRequest) (*http.Request, error) {
-	return NewRequest(ctx, "POST", "completions", request)
+// It takes a context and a CompletionRequest and returns an HTTP request ready to be sent.
+func (c *Client) NewCompletionReq(ctx context.Context, request CompletionRequest) (*http.Request, error) {
+	return c.NewRequest(ctx, "POST", "completions", request)
 }
 
 // ChatCompletionRequest represents a request to the OpenRouter chat completions API.
 // Based on https://openrouter.ai/docs/api-reference/chat-completion as of 2025-05-19.
 type ChatCompletionRequest struct {
 	BaseRequest
-	ToolsEnabled          // the api documentation doesnt mention it but the tools does
-	ResponseFormatEnabled // the api documentation doesnt mention it but the structured responses doc does
+	ToolsEnabled // the api documentation doesnt mention it but the tools does
 
 	Model    string        `json:"model"`
 	Messages []ChatMessage `json:"messages"`
+
+	// the api documentation doesnt mention it but the structured responses doc does
+	ResponseFormat *ResponseFormatEnabled `json:"response_format,omitempty"`
 }
 
 // NewChatCompletionReq creates a new HTTP request for the OpenRouter chat completions API.
 // It takes a context and a ChatCompletionRequest and returns an HTTP request ready to be sent.
-func NewChatCompletionReq(ctx context.Context, request ChatCompletionRequest) (*http.Request, error) {
-	return NewRequest(ctx, "POST", "chat/completions", request)
+func (c *Client) NewChatCompletionReq(ctx context.Context, request ChatCompletionRequest) (*http.Request, error) {
+	return c.NewRequest(ctx, "POST", "chat/completions", request)
 }
 
 // BaseRequest contains common fields used in both CompletionRequest and ChatCompletionRequest.
@@ -75,6 +77,10 @@ type ChatMessage struct {
 	Index              int          `json:"index,omitempty"`
 	Message            *ChatMessage `json:"message,omitempty"`
 	ToolCalls          []ToolCall   `json:"tool_calls,omitempty"`
+
+	// set on "tool" role messages that carry the result of a tool call back to the model
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	Name       string `json:"name,omitempty"`
 }
 
 // ToolCall represents a call to a tool by the AI model.
@@ -117,6 +123,7 @@ const (
 // It allows setting maximum prices for the overall request, completion tokens, etc.
 type MaxPrice struct {
 	Price      *float64 `json:"price,omitempty"`
+	Prompt     *float64 `json:"prompt,omitempty"`
 	Completion *float64 `json:"completion,omitempty"`
 	Request    *float64 `json:"request,omitempty"`
 	Image      *float64 `json:"image,omitempty"`
@@ -150,10 +157,15 @@ type UsageRequest struct {
 }
 
 // CompletionResponse represents the response from the OpenRouter completions API.
-// It contains the generated text and related metadata.
+// It contains the generated text and related metadata such as provider, model, and token usage.
 type CompletionResponse struct {
-	ID      string            `json:"id,omitempty"`
-	Choices []ChoicesResponse `json:"choices,omitempty"`
+	ID       string            `json:"id,omitempty"`
+	Provider string            `json:"provider,omitempty"`
+	Model    string            `json:"model,omitempty"`
+	Object   string            `json:"object,omitempty"`
+	Created  *int              `json:"created,omitempty"`
+	Choices  []ChoicesResponse `json:"choices,omitempty"`
+	Usage    *UsageResponse    `json:"usage,omitempty"`
 }
 
 // ChoicesResponse represents a single choice in the completion response.
@@ -179,15 +191,51 @@ type ChatCompletionResponse struct {
 }
 
 // ChatChoicesResponse represents a single choice in the chat completion response.
-// It contains the message generated by the AI model.
+// It contains the message generated by the AI model, or the incremental delta when streaming.
 type ChatChoicesResponse struct {
-	Message *ChatMessage `json:"message,omitempty"`
+	Index              int          `json:"index,omitempty"`
+	Message            *ChatMessage `json:"message,omitempty"`
+	Delta              *ChatMessage `json:"delta,omitempty"`
+	FinishReason       string       `json:"finish_reason,omitempty"`
+	NativeFinishReason string       `json:"native_finish_reason,omitempty"`
 }
 
 // UsageResponse contains information about token usage in the API request and response.
 // It includes counts for prompt tokens, completion tokens, and the total number of tokens used.
+// The details and cost are only sent when usage accounting is requested with UsageRequest.
+// See https://openrouter.ai/docs/use-cases/usage-accounting as of 2025-06-09.
 type UsageResponse struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens            int                      `json:"prompt_tokens"`
+	CompletionTokens        int                      `json:"completion_tokens"`
+	TotalTokens             int                      `json:"total_tokens"`
+	PromptTokensDetails     *PromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *CompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+	// Cost is the amount charged for the call in credits, which are US dollars
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// PromptTokensDetails breaks down the prompt tokens in a UsageResponse
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CompletionTokensDetails breaks down the completion tokens in a UsageResponse
+type CompletionTokensDetails struct {
+	ReasoningTokens int `json:"reasoning_tokens"`
+}
+
+// CachedTokens returns the number of prompt tokens read from the provider's cache
+func (u UsageResponse) CachedTokens() int {
+	if u.PromptTokensDetails == nil {
+		return 0
+	}
+	return u.PromptTokensDetails.CachedTokens
+}
+
+// ReasoningTokens returns the number of completion tokens spent on reasoning
+func (u UsageResponse) ReasoningTokens() int {
+	if u.CompletionTokensDetails == nil {
+		return 0
+	}
+	return u.CompletionTokensDetails.ReasoningTokens
 }
@@ -0,0 +1,190 @@
+package openrouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// APIError is an error returned by the OpenRouter API.
+// OpenRouter wraps errors in an envelope of the form
+// {"error":{"code":402,"message":"...","metadata":{...}}} where the metadata carries
+// the raw provider error or the moderation reasons, depending on the failure.
+// Based on https://openrouter.ai/docs/api-reference/errors as of 2025-05-21.
+type APIError struct {
+	// StatusCode is the HTTP status of the response, or the envelope code for errors sent mid-stream
+	StatusCode int
+	// Code is the error code from the envelope, usually the same as the HTTP status
+	Code int
+	// Message is the human readable error message
+	Message string
+	// ProviderName is the upstream provider that produced the error, if any
+	ProviderName string
+	// ProviderRaw is the raw error returned by the upstream provider, if any
+	ProviderRaw string
+	// Reasons lists why the input was flagged when the error is a moderation error
+	Reasons []string
+	// FlaggedInput is the segment of the input that was flagged by moderation
+	FlaggedInput string
+	// ModelSlug is the model the moderation error applies to
+	ModelSlug string
+	// Metadata holds the undecoded metadata object
+	Metadata map[string]any
+	// Body is the response body the error was decoded from
+	Body string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "openrouter error %d", e.StatusCode)
+
+	if e.Message != "" {
+		fmt.Fprintf(&sb, ": %s", e.Message)
+	} else if e.Body != "" {
+		fmt.Fprintf(&sb, ": %s", e.Body)
+	}
+
+	if e.ProviderName != "" {
+		fmt.Fprintf(&sb, " (provider: %s)", e.ProviderName)
+	}
+
+	if len(e.Reasons) > 0 {
+		fmt.Fprintf(&sb, " (flagged: %s)", strings.Join(e.Reasons, ", "))
+	}
+
+	return sb.String()
+}
+
+// errorEnvelope is the wire format of an OpenRouter error
+type errorEnvelope struct {
+	Error *struct {
+		Code     any            `json:"code"`
+		Message  string         `json:"message"`
+		Metadata map[string]any `json:"metadata"`
+	} `json:"error"`
+}
+
+// ParseAPIError builds an APIError from a response status code and body.
+// Bodies that are not an OpenRouter error envelope still produce an APIError carrying the status and body.
+func ParseAPIError(statusCode int, body []byte) *APIError {
+	apiErr, ok := decodeAPIError(body)
+	if !ok {
+		apiErr = &APIError{Code: statusCode}
+	}
+
+	apiErr.StatusCode = statusCode
+	apiErr.Body = strings.TrimSpace(string(body))
+	return apiErr
+}
+
+// decodeAPIError decodes an error envelope, the second return value is false if body is not one.
+func decodeAPIError(body []byte) (*APIError, bool) {
+	var envelope errorEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error == nil {
+		return nil, false
+	}
+
+	apiErr := &APIError{
+		Code:     parseCode(envelope.Error.Code),
+		Message:  envelope.Error.Message,
+		Metadata: envelope.Error.Metadata,
+		Body:     strings.TrimSpace(string(body)),
+	}
+	apiErr.StatusCode = apiErr.Code
+
+	if name, ok := apiErr.Metadata["provider_name"].(string); ok {
+		apiErr.ProviderName = name
+	}
+
+	switch raw := apiErr.Metadata["raw"].(type) {
+	case nil:
+	case string:
+		apiErr.ProviderRaw = raw
+	default:
+		if b, err := json.Marshal(raw); err == nil {
+			apiErr.ProviderRaw = string(b)
+		}
+	}
+
+	if reasons, ok := apiErr.Metadata["reasons"].([]any); ok {
+		for _, reason := range reasons {
+			if s, ok := reason.(string); ok {
+				apiErr.Reasons = append(apiErr.Reasons, s)
+			}
+		}
+	}
+
+	if flagged, ok := apiErr.Metadata["flagged_input"].(string); ok {
+		apiErr.FlaggedInput = flagged
+	}
+
+	if slug, ok := apiErr.Metadata["model_slug"].(string); ok {
+		apiErr.ModelSlug = slug
+	}
+
+	return apiErr, true
+}
+
+// parseCode converts the envelope code, which is usually a number but is sometimes sent as a string
+func parseCode(code any) int {
+	switch c := code.(type) {
+	case float64:
+		return int(c)
+	case string:
+		if i, err := strconv.Atoi(c); err == nil {
+			return i
+		}
+	}
+	return 0
+}
+
+// hasCode reports whether the error is an APIError with the given status or code
+func hasCode(err error, code int) (*APIError, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return nil, false
+	}
+	return apiErr, apiErr.StatusCode == code || apiErr.Code == code
+}
+
+// IsRateLimited reports whether err is an OpenRouter rate limit error
+func IsRateLimited(err error) bool {
+	_, ok := hasCode(err, http.StatusTooManyRequests)
+	return ok
+}
+
+// IsInsufficientCredits reports whether err was caused by the account or key running out of credits
+func IsInsufficientCredits(err error) bool {
+	_, ok := hasCode(err, http.StatusPaymentRequired)
+	return ok
+}
+
+// IsModerated reports whether err was caused by the input being flagged by moderation
+func IsModerated(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return len(apiErr.Reasons) > 0 || apiErr.StatusCode == http.StatusForbidden
+}
+
+// IsContextLengthExceeded reports whether err was caused by the prompt being too long for the model.
+// OpenRouter reports these as a 400 so the message is inspected to tell them apart from other bad requests.
+func IsContextLengthExceeded(err error) bool {
+	apiErr, ok := hasCode(err, http.StatusBadRequest)
+	if !ok {
+		return false
+	}
+
+	text := strings.ToLower(apiErr.Message + " " + apiErr.ProviderRaw)
+	for _, marker := range []string{"context length", "context_length", "context window", "maximum context", "too many tokens"} {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+	return false
+}
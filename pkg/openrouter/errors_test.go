@@ -0,0 +1,84 @@
+package openrouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseAPIError tests decoding the OpenRouter error envelope and the sentinel checks
+func TestParseAPIError(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          int
+		body            string
+		message         string
+		provider        string
+		reasons         []string
+		rateLimited     bool
+		noCredits       bool
+		moderated       bool
+		contextExceeded bool
+	}{
+		{
+			name:        "Rate limited",
+			status:      http.StatusTooManyRequests,
+			body:        `{"error":{"code":429,"message":"Rate limit exceeded"}}`,
+			message:     "Rate limit exceeded",
+			rateLimited: true,
+		},
+		{
+			name:      "Insufficient credits",
+			status:    http.StatusPaymentRequired,
+			body:      `{"error":{"code":402,"message":"Insufficient credits"}}`,
+			message:   "Insufficient credits",
+			noCredits: true,
+		},
+		{
+			name:      "Moderation",
+			status:    http.StatusForbidden,
+			body:      `{"error":{"code":403,"message":"flagged","metadata":{"reasons":["violence"],"flagged_input":"eat my shorts","provider_name":"OpenAI","model_slug":"openai/gpt-4o"}}}`,
+			message:   "flagged",
+			provider:  "OpenAI",
+			reasons:   []string{"violence"},
+			moderated: true,
+		},
+		{
+			name:            "Provider context length",
+			status:          http.StatusBadRequest,
+			body:            `{"error":{"code":400,"message":"Provider returned error","metadata":{"provider_name":"Anthropic","raw":{"error":{"message":"prompt is too long: maximum context length is 200000 tokens"}}}}}`,
+			message:         "Provider returned error",
+			provider:        "Anthropic",
+			contextExceeded: true,
+		},
+		{
+			name:   "Not an envelope",
+			status: http.StatusBadGateway,
+			body:   `<html>bad gateway</html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := ParseAPIError(tt.status, []byte(tt.body))
+			assert.Equal(t, tt.status, apiErr.StatusCode)
+			assert.Equal(t, tt.message, apiErr.Message)
+			assert.Equal(t, tt.provider, apiErr.ProviderName)
+			assert.Equal(t, tt.reasons, apiErr.Reasons)
+
+			// wrap the error the way callers see it
+			err := fmt.Errorf("calling openrouter: %w", &RetryError{Attempts: []error{errors.New("connection reset"), apiErr}})
+
+			var target *APIError
+			require.ErrorAs(t, err, &target, "errors.As should find the APIError")
+			assert.Equal(t, tt.rateLimited, IsRateLimited(err), "IsRateLimited")
+			assert.Equal(t, tt.noCredits, IsInsufficientCredits(err), "IsInsufficientCredits")
+			assert.Equal(t, tt.moderated, IsModerated(err), "IsModerated")
+			assert.Equal(t, tt.contextExceeded, IsContextLengthExceeded(err), "IsContextLengthExceeded")
+		})
+	}
+}
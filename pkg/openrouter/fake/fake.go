@@ -0,0 +1,521 @@
+// Package fake serves an OpenRouter compatible chat completions API that answers from a script,
+// so OpenRouter calls, tool loops and retries can be tested end to end without the network.
+package fake
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/openrouter"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultModel is listed by the models endpoint when the script lists none
+const DefaultModel = "fake/scripted"
+
+//go:embed default_script.json
+var defaultScriptJSON []byte
+
+// Script is the list of exchanges the fake answers with.
+// Each request is answered by the first unused exchange that matches it.
+type Script struct {
+	Exchanges []Exchange `json:"exchanges"`
+	// Models are listed by the models endpoint
+	Models []openrouter.Model `json:"models,omitempty"`
+}
+
+// Exchange is a scripted answer to a chat completion request
+type Exchange struct {
+	// Match selects the requests this answers, nil matches any request
+	Match *Match `json:"match,omitempty"`
+	// Repeat answers every matching request instead of only the first
+	Repeat bool `json:"repeat,omitempty"`
+
+	// Status is the HTTP status, 200 if not set
+	Status int `json:"status,omitempty"`
+	// Headers are added to the response, e.g. Retry-After
+	Headers map[string]string `json:"headers,omitempty"`
+	// Error is sent as an error envelope, mid-stream for streaming requests with a 200 status
+	Error *Error `json:"error,omitempty"`
+
+	// Response is a complete chat completion response sent as is, e.g. a captured one
+	Response json.RawMessage `json:"response,omitempty"`
+	// ResponseFile loads Response from a file, relative to the script
+	ResponseFile string `json:"response_file,omitempty"`
+
+	// Content is the assistant message content when no Response is given
+	Content string `json:"content,omitempty"`
+	// ToolCalls are the tool calls of the assistant message when no Response is given
+	ToolCalls []openrouter.ToolCall `json:"tool_calls,omitempty"`
+	// Usage is reported when the request asks for usage, counted from the words if not set
+	Usage *openrouter.UsageResponse `json:"usage,omitempty"`
+	// Chunks splits Content into these deltas when streaming, words are used if not set
+	Chunks []string `json:"chunks,omitempty"`
+}
+
+// Match selects the requests an exchange answers, every field that is set must match
+type Match struct {
+	// Contains must appear in the last user message, ignoring case
+	Contains string `json:"contains,omitempty"`
+	// LastRole is the role of the last message, e.g. "tool" for the turn after a tool call
+	LastRole string `json:"last_role,omitempty"`
+	// Model is the requested model
+	Model string `json:"model,omitempty"`
+	// Stream is whether the request streams
+	Stream *bool `json:"stream,omitempty"`
+}
+
+// Error is an OpenRouter error envelope
+type Error struct {
+	Code     int            `json:"code"`
+	Message  string         `json:"message"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// LoadScript reads a JSON or YAML (.yaml or .yml) script
+func LoadScript(path string) (Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Script{}, fmt.Errorf("error reading script: %w", err)
+	}
+
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		// go through JSON so the script types only need json tags
+		var doc any
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return Script{}, fmt.Errorf("error parsing script %s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return Script{}, fmt.Errorf("error converting script %s: %w", path, err)
+		}
+	}
+
+	script, err := parseScript(data)
+	if err != nil {
+		return Script{}, fmt.Errorf("error parsing script %s: %w", path, err)
+	}
+
+	for i, exchange := range script.Exchanges {
+		if exchange.ResponseFile == "" {
+			continue
+		}
+
+		response, err := os.ReadFile(filepath.Join(filepath.Dir(path), exchange.ResponseFile))
+		if err != nil {
+			return Script{}, fmt.Errorf("error reading response file for exchange %d: %w", i, err)
+		}
+		script.Exchanges[i].Response = response
+	}
+
+	return script, nil
+}
+
+// DefaultScript returns a script that answers every request with a couple of Simpsons quotes
+func DefaultScript() Script {
+	script, err := parseScript(defaultScriptJSON)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded openrouter script: %v", err))
+	}
+	return script
+}
+
+// parseScript decodes a JSON script, rejecting unknown fields so typos are caught
+func parseScript(data []byte) (Script, error) {
+	var script Script
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&script); err != nil {
+		return Script{}, err
+	}
+	return script, nil
+}
+
+// Server is an http.Handler that answers chat completion requests from a Script.
+// Requests are checked against the shape of openrouter.ChatCompletionRequest and rejected with a 400
+// if they don't fit. A request no exchange matches is answered with a 501.
+type Server struct {
+	script Script
+
+	mu       sync.Mutex
+	used     []bool
+	requests []openrouter.ChatCompletionRequest
+}
+
+// NewServer creates a Server for script
+func NewServer(script Script) *Server {
+	return &Server{script: script, used: make([]bool, len(script.Exchanges))}
+}
+
+// Start serves script on a local port, close the returned server when done
+func Start(script Script) *httptest.Server {
+	return httptest.NewServer(NewServer(script))
+}
+
+// Requests returns the chat completion requests received so far
+func (s *Server) Requests() []openrouter.ChatCompletionRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]openrouter.ChatCompletionRequest(nil), s.requests...)
+}
+
+// ServeHTTP implements http.Handler, any path prefix before the endpoint, e.g. /api/v1/, is ignored
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debug().Str("method", r.Method).Str("url", r.URL.String()).Msg("fake openrouter request")
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		s.chatCompletions(w, r)
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/models"):
+		s.models(w)
+	default:
+		writeError(w, http.StatusNotFound, Error{Code: http.StatusNotFound, Message: "fake openrouter: no such endpoint " + r.URL.Path})
+	}
+}
+
+// models lists the script's models
+func (s *Server) models(w http.ResponseWriter) {
+	models := s.script.Models
+	if len(models) == 0 {
+		models = []openrouter.Model{{
+			ID:                  DefaultModel,
+			Name:                "Fake: Scripted",
+			ContextLength:       8192,
+			Architecture:        openrouter.ModelArchitecture{Modality: "text->text"},
+			Pricing:             openrouter.ModelPricing{Prompt: "0", Completion: "0"},
+			SupportedParameters: []string{"tools", "response_format", "structured_outputs", "seed", "temperature"},
+		}}
+	}
+
+	writeJSON(w, http.StatusOK, openrouter.ModelsResponse{Data: models})
+}
+
+// chatCompletions answers a chat completion request
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var request openrouter.ChatCompletionRequest
+	decoder := json.NewDecoder(r.Body)
+	// OpenRouter ignores fields it doesn't know, e.g. a response format flattened into the top level
+	// instead of nested under response_format, so the fake rejects them to surface the mistake
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, Error{Code: http.StatusBadRequest, Message: "fake openrouter: invalid request: " + err.Error()})
+		return
+	}
+
+	if err := validate(request); err != nil {
+		writeError(w, http.StatusBadRequest, Error{Code: http.StatusBadRequest, Message: "fake openrouter: invalid request: " + err.Error()})
+		return
+	}
+
+	exchange, ok := s.next(request)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, Error{Code: http.StatusNotImplemented, Message: "fake openrouter: no scripted response matches the request"})
+		return
+	}
+
+	for key, value := range exchange.Headers {
+		w.Header().Set(key, value)
+	}
+
+	status := exchange.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if exchange.Error != nil && !(request.Stream && status == http.StatusOK) {
+		writeError(w, status, *exchange.Error)
+		return
+	}
+
+	if request.Stream {
+		s.stream(w, request, exchange)
+		return
+	}
+
+	if len(exchange.Response) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(exchange.Response)
+		return
+	}
+
+	response := newResponse(request)
+	response.Choices = []openrouter.ChatChoicesResponse{{
+		Message:      &openrouter.ChatMessage{Role: "assistant", Content: exchange.Content, ToolCalls: exchange.ToolCalls},
+		FinishReason: finishReason(exchange),
+	}}
+	response.Usage = usage(request, exchange)
+
+	writeJSON(w, status, response)
+}
+
+// stream answers a streaming request with server-sent events
+func (s *Server) stream(w http.ResponseWriter, request openrouter.ChatCompletionRequest, exchange Exchange) {
+	if len(exchange.Response) > 0 {
+		// stream a captured response as a single chunk with its message as the delta
+		var captured openrouter.ChatCompletionResponse
+		if err := json.Unmarshal(exchange.Response, &captured); err == nil {
+			for i := range captured.Choices {
+				captured.Choices[i].Delta, captured.Choices[i].Message = captured.Choices[i].Message, nil
+			}
+			exchange.Content = ""
+			exchange.ToolCalls = nil
+			if len(captured.Choices) > 0 && captured.Choices[0].Delta != nil {
+				exchange.Content = captured.Choices[0].Delta.Content
+				exchange.ToolCalls = captured.Choices[0].Delta.ToolCalls
+			}
+			if exchange.Usage == nil {
+				exchange.Usage = captured.Usage
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	send := func(data any) {
+		b, _ := json.Marshal(data)
+		fmt.Fprintf(w, "data: %s\n\n", b)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	fmt.Fprint(w, ": OPENROUTER PROCESSING\n\n")
+
+	chunk := func(delta openrouter.ChatMessage, finish string) openrouter.ChatCompletionResponse {
+		response := newResponse(request)
+		response.Object = "chat.completion.chunk"
+		response.Choices = []openrouter.ChatChoicesResponse{{Delta: &delta, FinishReason: finish}}
+		return response
+	}
+
+	send(chunk(openrouter.ChatMessage{Role: "assistant"}, ""))
+
+	for _, piece := range chunks(exchange) {
+		send(chunk(openrouter.ChatMessage{Content: piece}, ""))
+	}
+
+	// send each tool call's arguments in two halves to exercise the client's accumulation
+	for i, call := range exchange.ToolCalls {
+		arguments := ""
+		if call.Function != nil {
+			arguments = call.Function.Arguments
+		}
+		half := len(arguments) / 2
+
+		first := call
+		first.Index = i
+		first.Function = &openrouter.Function{Arguments: arguments[:half]}
+		if call.Function != nil {
+			first.Function.Name = call.Function.Name
+		}
+		send(chunk(openrouter.ChatMessage{ToolCalls: []openrouter.ToolCall{first}}, ""))
+
+		rest := openrouter.ToolCall{Index: i, Function: &openrouter.Function{Arguments: arguments[half:]}}
+		send(chunk(openrouter.ChatMessage{ToolCalls: []openrouter.ToolCall{rest}}, ""))
+	}
+
+	if exchange.Error != nil {
+		send(map[string]any{"error": exchange.Error})
+		return
+	}
+
+	send(chunk(openrouter.ChatMessage{}, finishReason(exchange)))
+
+	if u := usage(request, exchange); u != nil {
+		final := newResponse(request)
+		final.Object = "chat.completion.chunk"
+		final.Choices = []openrouter.ChatChoicesResponse{}
+		final.Usage = u
+		send(final)
+	}
+
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// next returns the exchange for a request and records the request
+func (s *Server) next(request openrouter.ChatCompletionRequest) (Exchange, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, request)
+
+	for i, exchange := range s.script.Exchanges {
+		if s.used[i] || !exchange.Match.matches(request) {
+			continue
+		}
+
+		if !exchange.Repeat {
+			s.used[i] = true
+		}
+		return exchange, true
+	}
+
+	return Exchange{}, false
+}
+
+// matches reports whether a request is selected by the match
+func (m *Match) matches(request openrouter.ChatCompletionRequest) bool {
+	if m == nil {
+		return true
+	}
+
+	if m.Model != "" && m.Model != request.Model {
+		return false
+	}
+
+	if m.Stream != nil && *m.Stream != request.Stream {
+		return false
+	}
+
+	if m.LastRole != "" && (len(request.Messages) == 0 || request.Messages[len(request.Messages)-1].Role != m.LastRole) {
+		return false
+	}
+
+	if m.Contains != "" {
+		last := ""
+		for _, message := range request.Messages {
+			if message.Role == "user" {
+				last = message.Content
+			}
+		}
+		if !strings.Contains(strings.ToLower(last), strings.ToLower(m.Contains)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// validRoles are the message roles the chat completions endpoint accepts
+var validRoles = map[string]bool{"system": true, "developer": true, "user": true, "assistant": true, "tool": true}
+
+// validate checks the parts of a request that OpenRouter would reject
+func validate(request openrouter.ChatCompletionRequest) error {
+	if request.Model == "" && len(request.Models) == 0 {
+		return fmt.Errorf("model is required")
+	}
+
+	if len(request.Messages) == 0 {
+		return fmt.Errorf("messages must not be empty")
+	}
+
+	for i, message := range request.Messages {
+		if !validRoles[message.Role] {
+			return fmt.Errorf("messages[%d]: invalid role %q", i, message.Role)
+		}
+		if message.Role == "tool" && message.ToolCallID == "" {
+			return fmt.Errorf("messages[%d]: tool messages need a tool_call_id", i)
+		}
+	}
+
+	for i, tool := range request.Tools {
+		if tool.Type != "function" || tool.Function.Name == "" {
+			return fmt.Errorf("tools[%d]: must be a named function", i)
+		}
+	}
+
+	if format := request.ResponseFormat; format != nil && format.Type == "json_schema" {
+		if format.JSONSchema == nil || format.JSONSchema.Schema == nil {
+			return fmt.Errorf("response_format: json_schema.schema is required")
+		}
+		if format.JSONSchema.Name == "" {
+			return fmt.Errorf("response_format: json_schema.name is required")
+		}
+	}
+
+	return nil
+}
+
+// newResponse creates a response with the metadata OpenRouter sends
+func newResponse(request openrouter.ChatCompletionRequest) openrouter.ChatCompletionResponse {
+	model := request.Model
+	if model == "" || model == openrouter.DefaultModel {
+		model = DefaultModel
+	}
+
+	created := int(time.Now().Unix())
+	return openrouter.ChatCompletionResponse{
+		ID:       fmt.Sprintf("gen-fake-%d", time.Now().UnixNano()),
+		Provider: "Fake",
+		Model:    model,
+		Object:   "chat.completion",
+		Created:  &created,
+	}
+}
+
+// finishReason is tool_calls if the exchange calls tools, otherwise stop
+func finishReason(exchange Exchange) string {
+	if len(exchange.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+// chunks splits the content into the streamed deltas
+func chunks(exchange Exchange) []string {
+	if len(exchange.Chunks) > 0 {
+		return exchange.Chunks
+	}
+
+	var pieces []string
+	content := exchange.Content
+	for content != "" {
+		i := strings.IndexByte(content[1:], ' ')
+		if i < 0 {
+			pieces = append(pieces, content)
+			break
+		}
+		pieces = append(pieces, content[:i+1])
+		content = content[i+1:]
+	}
+	return pieces
+}
+
+// usage returns the usage to report if the request asked for it
+func usage(request openrouter.ChatCompletionRequest, exchange Exchange) *openrouter.UsageResponse {
+	if request.Usage == nil || !request.Usage.Include {
+		return nil
+	}
+
+	if exchange.Usage != nil {
+		return exchange.Usage
+	}
+
+	prompt := 0
+	for _, message := range request.Messages {
+		prompt += len(strings.Fields(message.Content))
+	}
+	completion := len(strings.Fields(exchange.Content))
+
+	return &openrouter.UsageResponse{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+	}
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an OpenRouter error envelope
+func writeError(w http.ResponseWriter, status int, e Error) {
+	writeJSON(w, status, map[string]Error{"error": e})
+}
@@ -0,0 +1,138 @@
+package fake_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kklipsch/billy-bot/pkg/openrouter"
+	"github.com/kklipsch/billy-bot/pkg/openrouter/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newClient creates an OpenRouter client talking to server
+func newClient(url string) *openrouter.Client {
+	client := openrouter.NewClient("test-key")
+	client.BaseURL = url
+	return client
+}
+
+// TestToolLoop tests a tool loop against the captured example.json tool calls
+func TestToolLoop(t *testing.T) {
+	script, err := fake.LoadScript("testdata/tools.yaml")
+	require.NoError(t, err)
+
+	server := fake.NewServer(script)
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	var quotes []string
+	runner := openrouter.NewToolRunner()
+	openrouter.RegisterTool(runner, openrouter.Function{Name: "frinkiac"}, func(_ context.Context, args struct{ Quote string }) (any, error) {
+		quotes = append(quotes, args.Quote)
+		return "no scenes found", nil
+	})
+
+	request := openrouter.ChatCompletionRequest{Messages: []openrouter.ChatMessage{{Role: "user", Content: "what a classy outfit"}}}
+	result, messages := runner.Run(context.Background(), newClient(httpServer.URL), request)
+	require.NoError(t, result.Err)
+
+	assert.Equal(t, []string{"looking very classy", "classy", "classy outfit"}, quotes)
+	assert.Equal(t, `[{"quote":"looking very classy","confidence":0.8}]`, result.Result.Choices[0].Message.Content)
+	assert.InDelta(t, 0.0002, result.Result.Usage.Cost, 1e-9)
+	assert.Len(t, messages, 6, "The conversation should hold the question, the tool calls, their results and the answer")
+
+	requests := server.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "tool", requests[1].Messages[len(requests[1].Messages)-1].Role)
+
+	result, _ = runner.Run(context.Background(), newClient(httpServer.URL), request)
+	require.Error(t, result.Err, "Exchanges that don't repeat should only be used once")
+	assert.Contains(t, result.Err.Error(), "no scripted response")
+}
+
+// TestStream tests that scripted content and tool calls are streamed in chunks with usage
+func TestStream(t *testing.T) {
+	server := fake.Start(fake.Script{Exchanges: []fake.Exchange{{
+		Match:   &fake.Match{Contains: "SMART"},
+		Content: "I am so smart! S-M-R-T!",
+		ToolCalls: []openrouter.ToolCall{{
+			ID:       "call-1",
+			Type:     "function",
+			Function: &openrouter.Function{Name: "frinkiac", Arguments: `{"quote":"i am so smart"}`},
+		}},
+	}}})
+	defer server.Close()
+
+	events, err := newClient(server.URL).StreamChatCompletion(context.Background(), openrouter.ChatCompletionRequest{
+		Messages: []openrouter.ChatMessage{{Role: "user", Content: "homer is so smart"}},
+	})
+	require.NoError(t, err)
+
+	chunks := 0
+	response, err := openrouter.CollectStream(events, func(openrouter.ChatCompletionResponse) { chunks++ })
+	require.NoError(t, err)
+
+	assert.Greater(t, chunks, 5, "The content and arguments should be split across chunks")
+	require.Len(t, response.Choices, 1)
+	assert.Equal(t, "I am so smart! S-M-R-T!", response.Choices[0].Message.Content)
+	assert.Equal(t, "tool_calls", response.Choices[0].FinishReason)
+	require.Len(t, response.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, `{"quote":"i am so smart"}`, response.Choices[0].Message.ToolCalls[0].Function.Arguments)
+	require.NotNil(t, response.Usage, "Usage should be counted when it isn't scripted")
+	assert.Equal(t, 5, response.Usage.CompletionTokens)
+}
+
+// TestRetry tests that a scripted rate limit is retried and errors are returned as OpenRouter errors
+func TestRetry(t *testing.T) {
+	server := fake.Start(fake.Script{Exchanges: []fake.Exchange{
+		{Status: http.StatusTooManyRequests, Headers: map[string]string{"Retry-After": "0"}, Error: &fake.Error{Code: 429, Message: "slow down"}},
+		{Content: "d'oh"},
+		{Status: http.StatusPaymentRequired, Error: &fake.Error{Code: 402, Message: "no credits"}},
+	}})
+	defer server.Close()
+
+	client := newClient(server.URL)
+	client.Retry.InitialBackoff = time.Millisecond
+
+	request := openrouter.ChatCompletionRequest{Messages: []openrouter.ChatMessage{{Role: "user", Content: "hi"}}}
+
+	result := client.ChatCompletion(context.Background(), request)
+	require.NoError(t, result.Err, "The rate limit should be retried")
+	assert.Equal(t, "d'oh", result.Result.Choices[0].Message.Content)
+
+	result = client.ChatCompletion(context.Background(), request)
+	var apiErr *openrouter.APIError
+	require.ErrorAs(t, result.Err, &apiErr)
+	assert.Equal(t, http.StatusPaymentRequired, apiErr.Code)
+	assert.Equal(t, "no credits", apiErr.Message)
+}
+
+// TestValidation tests that requests that don't fit the chat completion shape are rejected
+func TestValidation(t *testing.T) {
+	server := fake.Start(fake.DefaultScript())
+	defer server.Close()
+
+	client := newClient(server.URL)
+	client.Retry = openrouter.NoRetry()
+
+	result := client.ChatCompletion(context.Background(), openrouter.ChatCompletionRequest{})
+	var apiErr *openrouter.APIError
+	require.ErrorAs(t, result.Err, &apiErr)
+	assert.Equal(t, http.StatusBadRequest, apiErr.Code)
+
+	flattened := `{"model":"fake/scripted","messages":[{"role":"user","content":"classy"}],"type":"json_schema","json_schema":{"type":"array"}}`
+	resp, err := http.Post(server.URL+"/chat/completions", "application/json", strings.NewReader(flattened))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "A response format outside of response_format should be rejected")
+
+	models, err := client.ListModels(context.Background())
+	require.NoError(t, err)
+	require.Len(t, models, 1)
+	assert.True(t, models[0].Supports("structured_outputs"))
+}
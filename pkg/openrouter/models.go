@@ -0,0 +1,191 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUnknownModel is returned when a model is not listed in the OpenRouter catalog
+var ErrUnknownModel = errors.New("unknown model")
+
+// Model describes a model available through OpenRouter.
+// Based on https://openrouter.ai/docs/api-reference/list-available-models as of 2025-06-02.
+type Model struct {
+	ID                  string            `json:"id"`
+	CanonicalSlug       string            `json:"canonical_slug,omitempty"`
+	Name                string            `json:"name,omitempty"`
+	Created             int64             `json:"created,omitempty"`
+	Description         string            `json:"description,omitempty"`
+	ContextLength       int               `json:"context_length,omitempty"`
+	Architecture        ModelArchitecture `json:"architecture"`
+	Pricing             ModelPricing      `json:"pricing"`
+	TopProvider         ModelTopProvider  `json:"top_provider"`
+	SupportedParameters []string          `json:"supported_parameters,omitempty"`
+}
+
+// ModelArchitecture describes the inputs and outputs a model handles
+type ModelArchitecture struct {
+	Modality         string   `json:"modality,omitempty"`
+	InputModalities  []string `json:"input_modalities,omitempty"`
+	OutputModalities []string `json:"output_modalities,omitempty"`
+	Tokenizer        string   `json:"tokenizer,omitempty"`
+	InstructType     *string  `json:"instruct_type,omitempty"`
+}
+
+// ModelPricing holds the price in US dollars per token, request or image.
+// OpenRouter sends prices as decimal strings to avoid rounding.
+type ModelPricing struct {
+	Prompt            string `json:"prompt,omitempty"`
+	Completion        string `json:"completion,omitempty"`
+	Request           string `json:"request,omitempty"`
+	Image             string `json:"image,omitempty"`
+	WebSearch         string `json:"web_search,omitempty"`
+	InternalReasoning string `json:"internal_reasoning,omitempty"`
+	InputCacheRead    string `json:"input_cache_read,omitempty"`
+	InputCacheWrite   string `json:"input_cache_write,omitempty"`
+}
+
+// PromptPrice returns the price of a prompt token in dollars, 0 if unknown
+func (p ModelPricing) PromptPrice() float64 {
+	return parsePrice(p.Prompt)
+}
+
+// CompletionPrice returns the price of a completion token in dollars, 0 if unknown
+func (p ModelPricing) CompletionPrice() float64 {
+	return parsePrice(p.Completion)
+}
+
+// parsePrice parses a decimal price string, treating anything unparsable as free
+func parsePrice(price string) float64 {
+	f, err := strconv.ParseFloat(price, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// ModelTopProvider describes the limits of the provider OpenRouter routes to by default
+type ModelTopProvider struct {
+	ContextLength       *int `json:"context_length,omitempty"`
+	MaxCompletionTokens *int `json:"max_completion_tokens,omitempty"`
+	IsModerated         bool `json:"is_moderated,omitempty"`
+}
+
+// Supports reports whether the model lists param, e.g. "tools" or "structured_outputs", in its supported parameters
+func (m Model) Supports(param string) bool {
+	return slices.Contains(m.SupportedParameters, param)
+}
+
+// ModelsResponse is the response from the models endpoint
+type ModelsResponse struct {
+	Data []Model `json:"data"`
+}
+
+// ListModels fetches the catalog of models from the models endpoint
+func (c *Client) ListModels(ctx context.Context) ([]Model, error) {
+	result := Get[ModelsResponse](ctx, c, "models")
+	if result.Err != nil {
+		return nil, fmt.Errorf("error listing models: %w", result.Err)
+	}
+	return result.Result.Data, nil
+}
+
+// FindModel returns the model with the given id from a catalog
+func FindModel(models []Model, id string) (Model, bool) {
+	i := slices.IndexFunc(models, func(m Model) bool { return m.ID == id || m.CanonicalSlug == id })
+	if i < 0 {
+		return Model{}, false
+	}
+	return models[i], true
+}
+
+// ValidateModel checks that id is in the catalog and returns the parameters in required that the model
+// does not list as supported. The auto router is always accepted as it picks a model per request.
+func ValidateModel(models []Model, id string, required ...string) ([]string, error) {
+	model, ok := FindModel(models, id)
+	if !ok {
+		if id == DefaultModel {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w %q, run `billy-bot models list` to see the available models", ErrUnknownModel, id)
+	}
+
+	var missing []string
+	for _, param := range required {
+		if !model.Supports(param) {
+			missing = append(missing, param)
+		}
+	}
+	return missing, nil
+}
+
+// ModelCache stores the model catalog on disk so it is not fetched on every run
+type ModelCache struct {
+	// Path is the file the catalog is stored in
+	Path string
+	// TTL is how long a stored catalog is used before it is fetched again
+	TTL time.Duration
+}
+
+// DefaultModelCache returns a cache in the user's cache directory that is refreshed daily
+func DefaultModelCache() ModelCache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return ModelCache{
+		Path: filepath.Join(dir, "billy-bot", "models.json"),
+		TTL:  24 * time.Hour,
+	}
+}
+
+// modelCacheFile is the on disk format of the model cache
+type modelCacheFile struct {
+	BaseURL   string    `json:"base_url"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Models    []Model   `json:"models"`
+}
+
+// CachedModels returns the model catalog from the cache if it is fresh, otherwise it fetches
+// the catalog and stores it. Failing to read or write the cache is logged but not fatal.
+func (c *Client) CachedModels(ctx context.Context, cache ModelCache) ([]Model, error) {
+	baseURL := c.URL("")
+
+	if data, err := os.ReadFile(cache.Path); err == nil {
+		var stored modelCacheFile
+		if err := json.Unmarshal(data, &stored); err != nil {
+			log.Warn().Err(err).Str("path", cache.Path).Msg("ignoring unreadable model cache")
+		} else if stored.BaseURL == baseURL && time.Since(stored.FetchedAt) < cache.TTL {
+			log.Debug().Str("path", cache.Path).Time("fetched_at", stored.FetchedAt).Msg("using cached models")
+			return stored.Models, nil
+		}
+	}
+
+	models, err := c.ListModels(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(modelCacheFile{BaseURL: baseURL, FetchedAt: time.Now(), Models: models})
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(cache.Path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(cache.Path, data, 0o644)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("path", cache.Path).Msg("failed to write model cache")
+	}
+
+	return models, nil
+}
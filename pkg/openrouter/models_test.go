@@ -0,0 +1,79 @@
+package openrouter
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const modelsBody = `{"data":[
+	{"id":"openai/gpt-4o-mini","name":"OpenAI: GPT-4o-mini","context_length":128000,
+	 "architecture":{"modality":"text+image->text","input_modalities":["text","image"],"output_modalities":["text"]},
+	 "pricing":{"prompt":"0.00000015","completion":"0.0000006"},
+	 "supported_parameters":["tools","response_format","structured_outputs"]},
+	{"id":"gryphe/mythomax-l2-13b","context_length":4096,
+	 "architecture":{"modality":"text->text"},
+	 "pricing":{"prompt":"0.00000006","completion":"0.00000006"},
+	 "supported_parameters":["temperature"]}
+]}`
+
+// TestCachedModels tests that the catalog is fetched once and then served from the cache
+func TestCachedModels(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/models", r.URL.Path)
+		fmt.Fprint(w, modelsBody)
+	}))
+	defer server.Close()
+
+	client := NewClient("")
+	client.BaseURL = server.URL
+	cache := ModelCache{Path: filepath.Join(t.TempDir(), "models.json"), TTL: time.Hour}
+
+	models, err := client.CachedModels(context.Background(), cache)
+	require.NoError(t, err)
+	require.Len(t, models, 2)
+	assert.Equal(t, 128000, models[0].ContextLength)
+	assert.InDelta(t, 0.15, models[0].Pricing.PromptPrice()*1_000_000, 1e-9)
+	assert.Equal(t, []string{"text", "image"}, models[0].Architecture.InputModalities)
+
+	cached, err := client.CachedModels(context.Background(), cache)
+	require.NoError(t, err)
+	assert.Equal(t, models, cached, "The cached catalog should match the fetched one")
+	assert.Equal(t, 1, requests, "A fresh cache should not be refetched")
+
+	cache.TTL = 0
+	_, err = client.CachedModels(context.Background(), cache)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests, "An expired cache should be refetched")
+}
+
+// TestValidateModel tests checking models and their supported parameters against the catalog
+func TestValidateModel(t *testing.T) {
+	models := []Model{
+		{ID: "openai/gpt-4o-mini", SupportedParameters: []string{"tools", "structured_outputs"}},
+		{ID: "gryphe/mythomax-l2-13b", SupportedParameters: []string{"temperature"}},
+	}
+
+	missing, err := ValidateModel(models, "openai/gpt-4o-mini", "structured_outputs", "tools")
+	require.NoError(t, err)
+	assert.Empty(t, missing)
+
+	missing, err = ValidateModel(models, "gryphe/mythomax-l2-13b", "structured_outputs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"structured_outputs"}, missing)
+
+	_, err = ValidateModel(models, DefaultModel, "structured_outputs")
+	assert.NoError(t, err, "The auto router should always be accepted")
+
+	_, err = ValidateModel(models, "openai/gpt-5-turbo-max")
+	assert.ErrorIs(t, err, ErrUnknownModel)
+}
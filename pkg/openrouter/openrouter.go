@@ -13,42 +13,33 @@ import (
 )
 
 // Call makes an API call to OpenRouter with the provided request.
-// It handles adding default headers, sending the request, and processing the response.
+// It handles adding the client's headers, sending the request, and processing the response.
 // The generic type parameter T specifies the expected response type.
-func Call[T any](ctx context.Context, apiKey string, req *http.Request, err error, allowedStatus ...int) Response[T] {
+func Call[T any](ctx context.Context, client *Client, req *http.Request, err error, allowedStatus ...int) Response[T] {
 	if err != nil {
 		return Response[T]{Err: fmt.Errorf("error creating request: %w", err)}
 	}
 
-	AddDefaultHeaders(apiKey, req)
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := client.Do(req)
 	return FromResponse[T](ctx, resp, err, allowedStatus...)
 }
 
 // NewRequest creates a new HTTP request for the OpenRouter API.
-// It takes a context, HTTP method, API endpoint, and request body, and returns an HTTP request ready to be sent.
-func NewRequest(ctx context.Context, method string, endpoint string, body any) (*http.Request, error) {
+// It takes a context, HTTP method, API endpoint, and request body, and returns an HTTP request
+// against the client's base URL ready to be sent.
+func (c *Client) NewRequest(ctx context.Context, method string, endpoint string, body any) (*http.Request, error) {
 	requestJSON, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling request for %s: %w", endpoint, err)
 	}
 
-	url := fmt.Sprintf("https://openrouter.ai/api/v1/%s", endpoint)
+	url := c.URL(endpoint)
 
 	log.Debug().Str("url", url).Str("method", method).Bytes("body", requestJSON).Msg("sending to openrouter")
 
 	return http.NewRequestWithContext(ctx, method, url, strings.NewReader(string(requestJSON)))
 }
 
-// AddDefaultHeaders adds the required headers to an HTTP request for the OpenRouter API.
-// It sets the content type, authorization, referer, and title headers.
-func AddDefaultHeaders(APIKey string, req *http.Request) {
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+APIKey)
-	req.Header.Set("HTTP-Referer", "https://github.com/kklipsch/billy-bot")
-	req.Header.Set("X-Title", "Billy Bot")
-}
-
 // Response represents a response from the OpenRouter API.
 // It contains the raw response body, any error that occurred, and the parsed result.
 // The generic type parameter T specifies the expected response type.
@@ -81,7 +72,13 @@ func FromResponse[T any](ctx context.Context, resp *http.Response, err error, al
 	oresp.Body = strbody
 
 	if !slices.Contains(allowedStatus, resp.StatusCode) {
-		oresp.Err = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		oresp.Err = ParseAPIError(resp.StatusCode, body)
+		return
+	}
+
+	// openrouter can report a failure with a successful status once it has committed to the response
+	if apiErr, ok := decodeAPIError(body); ok {
+		oresp.Err = apiErr
 		return
 	}
 
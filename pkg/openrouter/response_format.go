@@ -4,17 +4,34 @@ import (
 	"github.com/kklipsch/billy-bot/pkg/jsonschema"
 )
 
-// ResponseFormatEnabled represents the response_format field in OpenRouter API requests
+// ResponseFormatEnabled represents the response_format field in OpenRouter API requests.
+// Described in https://openrouter.ai/docs/features/structured-outputs.
 type ResponseFormatEnabled struct {
-	Type       string             `json:"type"`
-	JSONSchema *jsonschema.Schema `json:"json_schema"`
+	Type       string          `json:"type"`
+	JSONSchema *JSONSchemaSpec `json:"json_schema,omitempty"`
 }
 
-// NewResponseFormatEnabled creates a new ResponseFormatEnabled with the given type and JSON schema
-func NewResponseFormatEnabled(name string, schema *jsonschema.Schema) ResponseFormatEnabled {
-	schema.Strict = true
-	return ResponseFormatEnabled{
-		Type:       "json_schema",
-		JSONSchema: schema,
+// JSONSchemaSpec names the schema a json_schema response format asks the model to follow
+type JSONSchemaSpec struct {
+	Name   string             `json:"name"`
+	Strict bool               `json:"strict"`
+	Schema *jsonschema.Schema `json:"schema"`
+}
+
+// NewResponseFormatEnabled creates a strict json_schema response format with the given name and JSON schema.
+// The schema is shared, not copied or modified, so package level schemas can be used concurrently.
+func NewResponseFormatEnabled(name string, schema *jsonschema.Schema) *ResponseFormatEnabled {
+	return &ResponseFormatEnabled{
+		Type: "json_schema",
+		JSONSchema: &JSONSchemaSpec{
+			Name:   name,
+			Strict: true,
+			Schema: schema,
+		},
 	}
 }
+
+// NewResponseFormatFor creates a new ResponseFormatEnabled using the JSON schema reflected from T
+func NewResponseFormatFor[T any](name string) *ResponseFormatEnabled {
+	return NewResponseFormatEnabled(name, jsonschema.Reflect[T]())
+}
@@ -0,0 +1,264 @@
+package openrouter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RetryPolicy controls how the Client retries failed requests.
+// Only failures that are safe to repeat are retried: the statuses in RetryableStatus, connections
+// that were never made and, for idempotent methods, transport errors such as connection resets or timeouts.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first, values below 1 mean a single attempt
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between attempts, a Retry-After longer than this ends the retries
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after each attempt
+	Multiplier float64
+	// Jitter randomizes each wait by up to this fraction in either direction, e.g. 0.2 for +/-20%
+	Jitter float64
+	// RetryableStatus lists the HTTP status codes that are retried
+	RetryableStatus []int
+}
+
+// DefaultRetryPolicy returns the policy used by NewClient: three attempts with exponential
+// backoff on timeouts, rate limits and server errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatus: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// NoRetry returns a policy that makes a single attempt.
+func NoRetry() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 1}
+}
+
+// RetryError is returned when every attempt of a request failed.
+// It wraps the cause of each attempt so errors.Is and errors.As see all of them.
+type RetryError struct {
+	Attempts []error
+}
+
+// Error implements the error interface
+func (e *RetryError) Error() string {
+	causes := make([]string, len(e.Attempts))
+	for i, err := range e.Attempts {
+		causes[i] = fmt.Sprintf("attempt %d: %v", i+1, err)
+	}
+	return fmt.Sprintf("request failed after %d attempts: %s", len(e.Attempts), strings.Join(causes, "; "))
+}
+
+// Unwrap returns the cause of every attempt
+func (e *RetryError) Unwrap() []error {
+	return e.Attempts
+}
+
+// do sends req with httpClient, retrying according to the policy.
+// Responses with a status that is not retryable are returned to the caller untouched.
+func (p RetryPolicy) do(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	var causes []error
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			body, err := rewindBody(req)
+			if err != nil {
+				return nil, &RetryError{Attempts: append(causes, err)}
+			}
+			req.Body = body
+		}
+
+		resp, err := httpClient.Do(req)
+
+		retryable := false
+		var cause error
+		var statusErr *APIError
+		switch {
+		case err != nil:
+			retryable = isRetryableError(req, err)
+			cause = err
+		case slices.Contains(p.RetryableStatus, resp.StatusCode):
+			retryable = true
+			statusErr = statusError(resp)
+			cause = statusErr
+		default:
+			return resp, nil
+		}
+
+		wait, ok := p.backoff(attempt, resp)
+		if !retryable || !ok || attempt >= p.MaxAttempts || req.Body != nil && req.GetBody == nil {
+			if len(causes) == 0 {
+				// nothing was retried, hand back exactly what the transport gave us
+				if statusErr != nil {
+					resp.Body = io.NopCloser(strings.NewReader(statusErr.Body))
+				}
+				return resp, err
+			}
+			return nil, &RetryError{Attempts: append(causes, cause)}
+		}
+
+		causes = append(causes, cause)
+
+		log.Warn().
+			Err(cause).
+			Int("attempt", attempt).
+			Int("max_attempts", p.MaxAttempts).
+			Dur("wait", wait).
+			Str("url", req.URL.String()).
+			Msg("retrying openrouter request")
+
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, &RetryError{Attempts: append(causes, err)}
+		}
+	}
+}
+
+// backoff returns how long to wait before the attempt following attempt.
+// A Retry-After header takes precedence over the exponential backoff; if it asks for a
+// longer wait than MaxBackoff the second return value is false and no retry should be made.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+				return wait, false
+			}
+			return wait, true
+		}
+	}
+
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	wait := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && wait > float64(p.MaxBackoff) {
+		wait = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		wait += wait * p.Jitter * (rand.Float64()*2 - 1)
+	}
+
+	return time.Duration(wait), true
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// isRetryableError reports whether a transport error is safe to retry. A connection that was never made
+// can always be retried, but one that broke may have delivered the request, so a POST that could already be
+// generating (and billing) a completion is only retried when the method is idempotent.
+func isRetryableError(req *http.Request, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var opErr *net.OpError
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	if !isIdempotent(req.Method) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// isIdempotent reports whether sending a request with method twice has the same effect as sending it once
+func isIdempotent(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// statusError drains and closes the body of a failed response so the connection can be reused
+func statusError(resp *http.Response) *APIError {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return &APIError{StatusCode: resp.StatusCode, Code: resp.StatusCode, Message: fmt.Sprintf("failed to read response body: %v", err)}
+	}
+
+	return ParseAPIError(resp.StatusCode, body)
+}
+
+// rewindBody returns a fresh copy of the request body for another attempt
+func rewindBody(req *http.Request) (io.ReadCloser, error) {
+	if req.Body == nil || req.GetBody == nil {
+		return req.Body, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("error rewinding request body: %w", err)
+	}
+	return body, nil
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,344 @@
+package openrouter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// streamDone is the data payload OpenRouter sends to mark the end of a stream.
+	streamDone = "[DONE]"
+
+	// maxStreamLineSize bounds a single SSE line; chunks carrying tool call arguments can be large.
+	maxStreamLineSize = 1024 * 1024
+)
+
+// StreamEvent is a single chunk received from a streaming completion, T is the chunk type,
+// e.g. ChatCompletionResponse. If Err is set the stream has failed and no further events will be sent.
+type StreamEvent[T any] struct {
+	Chunk T
+	Err   error
+}
+
+// CallStream makes a streaming API call to OpenRouter with the provided request.
+// The request body must have been built with Stream set to true.
+// The returned channel is closed when the stream ends, the context is cancelled, or an error occurs.
+func CallStream[T any](ctx context.Context, client *Client, req *http.Request, err error) (<-chan StreamEvent[T], error) {
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// reuse the non streaming handling so the error body is read and logged
+		result := FromResponse[T](ctx, resp, nil, http.StatusOK)
+		return nil, result.Err
+	}
+
+	return ReadStream[T](ctx, resp.Body), nil
+}
+
+// ReadStream parses OpenRouter server-sent events from body into chunks of type T.
+// Comment lines (keep-alives such as ": OPENROUTER PROCESSING") are ignored and the
+// stream ends at the "[DONE]" marker. The body is closed when the stream ends.
+func ReadStream[T any](ctx context.Context, body io.ReadCloser) <-chan StreamEvent[T] {
+	events := make(chan StreamEvent[T])
+
+	go func() {
+		defer close(events)
+		defer body.Close()
+
+		send := func(ev StreamEvent[T]) bool {
+			select {
+			case events <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var data bytes.Buffer
+		scanner := bufio.NewScanner(body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+		for scanner.Scan() {
+			line := scanner.Bytes()
+
+			switch {
+
+			// keep-alive or other comment
+			case bytes.HasPrefix(line, []byte(":")):
+				log.Trace().Bytes("comment", line).Msg("openrouter stream comment")
+
+				// event data, may be split across several lines
+			case bytes.HasPrefix(line, []byte("data:")):
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.Write(bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" ")))
+
+				// end of event
+			case len(line) == 0:
+				if data.Len() == 0 {
+					continue
+				}
+
+				payload := strings.TrimSpace(data.String())
+				data.Reset()
+
+				if payload == streamDone {
+					return
+				}
+
+				log.Trace().Str("data", payload).Msg("openrouter stream chunk")
+
+				// errors after the stream has started are sent as a chunk carrying an error envelope
+				if apiErr, ok := decodeAPIError([]byte(payload)); ok {
+					send(StreamEvent[T]{Err: apiErr})
+					return
+				}
+
+				var chunk T
+				if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+					send(StreamEvent[T]{Err: fmt.Errorf("error unmarshaling stream chunk: %w %s", err, payload)})
+					return
+				}
+
+				if !send(StreamEvent[T]{Chunk: chunk}) {
+					return
+				}
+
+				// other fields such as event: and id: are not used by openrouter
+			default:
+				log.Trace().Bytes("line", line).Msg("ignoring openrouter stream line")
+			}
+		}
+
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			send(StreamEvent[T]{Err: fmt.Errorf("error reading stream: %w", err)})
+			return
+		}
+
+		if ctx.Err() != nil {
+			send(StreamEvent[T]{Err: ctx.Err()})
+			return
+		}
+
+		// a stream that ends without [DONE] was cut off
+		send(StreamEvent[T]{Err: fmt.Errorf("stream ended before %s: %w", streamDone, io.ErrUnexpectedEOF)})
+	}()
+
+	return events
+}
+
+// StreamAccumulator merges streamed chunks into a single ChatCompletionResponse.
+// Content deltas are concatenated, tool call fragments are joined by their index and
+// the final usage block is kept.
+type StreamAccumulator struct {
+	response  ChatCompletionResponse
+	choices   map[int]*ChatChoicesResponse
+	toolCalls map[int]map[int]*ToolCall
+}
+
+// Add merges a single streamed chunk into the accumulated response.
+func (a *StreamAccumulator) Add(chunk ChatCompletionResponse) {
+	if a.choices == nil {
+		a.choices = make(map[int]*ChatChoicesResponse)
+		a.toolCalls = make(map[int]map[int]*ToolCall)
+	}
+
+	if chunk.ID != "" {
+		a.response.ID = chunk.ID
+	}
+	if chunk.Provider != "" {
+		a.response.Provider = chunk.Provider
+	}
+	if chunk.Model != "" {
+		a.response.Model = chunk.Model
+	}
+	if chunk.Created != nil {
+		a.response.Created = chunk.Created
+	}
+	if chunk.Usage != nil {
+		a.response.Usage = chunk.Usage
+	}
+
+	for _, c := range chunk.Choices {
+		choice, ok := a.choices[c.Index]
+		if !ok {
+			choice = &ChatChoicesResponse{Index: c.Index, Message: &ChatMessage{}}
+			a.choices[c.Index] = choice
+			a.toolCalls[c.Index] = make(map[int]*ToolCall)
+		}
+
+		if c.FinishReason != "" {
+			choice.FinishReason = c.FinishReason
+		}
+		if c.NativeFinishReason != "" {
+			choice.NativeFinishReason = c.NativeFinishReason
+		}
+
+		// some providers send a full message on the last chunk rather than a delta
+		delta := c.Delta
+		if delta == nil {
+			delta = c.Message
+		}
+		if delta == nil {
+			continue
+		}
+
+		if delta.Role != "" {
+			choice.Message.Role = delta.Role
+		}
+		choice.Message.Content += delta.Content
+
+		for _, tc := range delta.ToolCalls {
+			call, ok := a.toolCalls[c.Index][tc.Index]
+			if !ok {
+				call = &ToolCall{Index: tc.Index, Function: &Function{}}
+				a.toolCalls[c.Index][tc.Index] = call
+			}
+
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			if tc.Type != "" {
+				call.Type = tc.Type
+			}
+			if tc.Function != nil {
+				if tc.Function.Name != "" {
+					call.Function.Name = tc.Function.Name
+				}
+				call.Function.Arguments += tc.Function.Arguments
+			}
+		}
+	}
+}
+
+// Result returns the response accumulated so far, with choices and tool calls ordered by index.
+func (a *StreamAccumulator) Result() ChatCompletionResponse {
+	result := a.response
+	result.Object = "chat.completion"
+	result.Choices = nil
+
+	for _, index := range slices.Sorted(maps.Keys(a.choices)) {
+		choice := *a.choices[index]
+		message := *choice.Message
+		message.ToolCalls = nil
+
+		calls := a.toolCalls[index]
+		for _, callIndex := range slices.Sorted(maps.Keys(calls)) {
+			call := *calls[callIndex]
+			function := *call.Function
+			call.Function = &function
+			message.ToolCalls = append(message.ToolCalls, call)
+		}
+
+		choice.Message = &message
+		result.Choices = append(result.Choices, choice)
+	}
+
+	return result
+}
+
+// CollectStream drains a stream into a single response, calling onChunk (if not nil) as each chunk arrives.
+// It returns the accumulated response along with the first error encountered.
+func CollectStream(events <-chan StreamEvent[ChatCompletionResponse], onChunk func(ChatCompletionResponse)) (ChatCompletionResponse, error) {
+	acc := StreamAccumulator{}
+
+	for ev := range events {
+		if ev.Err != nil {
+			return acc.Result(), ev.Err
+		}
+
+		acc.Add(ev.Chunk)
+		if onChunk != nil {
+			onChunk(ev.Chunk)
+		}
+	}
+
+	return acc.Result(), nil
+}
+
+// CollectCompletionStream drains a text completion stream into a single response, calling onChunk
+// (if not nil) as each chunk arrives. The text of each choice is concatenated and the final usage block kept.
+// It returns the accumulated response along with the first error encountered.
+func CollectCompletionStream(events <-chan StreamEvent[CompletionResponse], onChunk func(CompletionResponse)) (CompletionResponse, error) {
+	var result CompletionResponse
+	choices := make(map[int]*ChoicesResponse)
+
+	collect := func() CompletionResponse {
+		result.Choices = nil
+		for _, index := range slices.Sorted(maps.Keys(choices)) {
+			result.Choices = append(result.Choices, *choices[index])
+		}
+		return result
+	}
+
+	for ev := range events {
+		if ev.Err != nil {
+			return collect(), ev.Err
+		}
+
+		chunk := ev.Chunk
+		if chunk.ID != "" {
+			result.ID = chunk.ID
+		}
+		if chunk.Provider != "" {
+			result.Provider = chunk.Provider
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Created != nil {
+			result.Created = chunk.Created
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage
+		}
+
+		for _, c := range chunk.Choices {
+			index := 0
+			if c.Index != nil {
+				index = *c.Index
+			}
+
+			choice, ok := choices[index]
+			if !ok {
+				choice = &ChoicesResponse{Index: &index}
+				choices[index] = choice
+			}
+
+			choice.Text += c.Text
+			if c.FinishReason != "" {
+				choice.FinishReason = c.FinishReason
+			}
+			if c.NativeFinishReason != "" {
+				choice.NativeFinishReason = c.NativeFinishReason
+			}
+		}
+
+		if onChunk != nil {
+			onChunk(chunk)
+		}
+	}
+
+	result.Object = "text_completion"
+	return collect(), nil
+}
@@ -0,0 +1,89 @@
+package openrouter
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReadStreamToolCalls tests that streamed tool call fragments are joined by index
+func TestReadStreamToolCalls(t *testing.T) {
+	body := `: OPENROUTER PROCESSING
+
+data: {"id":"gen-1","model":"openai/gpt-4o-mini","choices":[{"index":0,"delta":{"role":"assistant","content":"","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"frinkiac","arguments":""}}]}}]}
+
+data: {"id":"gen-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"quote\": "}}]}}]}
+
+: OPENROUTER PROCESSING
+
+data: {"id":"gen-1","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"classy\"}"}},{"index":1,"id":"call_2","type":"function","function":{"name":"frinkiac","arguments":"{}"}}]}}]}
+
+data: {"id":"gen-1","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":5,"total_tokens":15}}
+
+data: [DONE]
+
+`
+	events := ReadStream[ChatCompletionResponse](context.Background(), io.NopCloser(strings.NewReader(body)))
+
+	chunks := 0
+	result, err := CollectStream(events, func(ChatCompletionResponse) { chunks++ })
+	require.NoError(t, err, "CollectStream should not return an error")
+	assert.Equal(t, 4, chunks, "All data events should be delivered")
+
+	require.Len(t, result.Choices, 1, "There should be a single choice")
+	choice := result.Choices[0]
+	assert.Equal(t, "tool_calls", choice.FinishReason, "Finish reason should be kept")
+	assert.Equal(t, "assistant", choice.Message.Role, "Role should be kept")
+
+	require.Len(t, choice.Message.ToolCalls, 2, "Tool calls should be grouped by index")
+	assert.Equal(t, "call_1", choice.Message.ToolCalls[0].ID)
+	assert.Equal(t, "frinkiac", choice.Message.ToolCalls[0].Function.Name)
+	assert.Equal(t, `{"quote": "classy"}`, choice.Message.ToolCalls[0].Function.Arguments)
+	assert.Equal(t, "call_2", choice.Message.ToolCalls[1].ID)
+
+	require.NotNil(t, result.Usage, "Usage should be surfaced")
+	assert.Equal(t, 15, result.Usage.TotalTokens)
+}
+
+// TestReadStreamContent tests that content deltas are concatenated
+func TestReadStreamContent(t *testing.T) {
+	body := "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"[{\\\"quote\\\":\"}}]}\n\n" +
+		"data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\" \\\"d'oh\\\"}]\"}}]}\n\n" +
+		"data: [DONE]\n\n"
+
+	result, err := CollectStream(ReadStream[ChatCompletionResponse](context.Background(), io.NopCloser(strings.NewReader(body))), nil)
+	require.NoError(t, err, "CollectStream should not return an error")
+	require.Len(t, result.Choices, 1)
+	assert.Equal(t, `[{"quote": "d'oh"}]`, result.Choices[0].Message.Content)
+}
+
+// TestReadStreamTruncated tests that a stream without the done marker is reported as an error
+func TestReadStreamTruncated(t *testing.T) {
+	body := "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"partial\"}}]}\n\n"
+
+	result, err := CollectStream(ReadStream[ChatCompletionResponse](context.Background(), io.NopCloser(strings.NewReader(body))), nil)
+	require.Error(t, err, "A truncated stream should return an error")
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	require.Len(t, result.Choices, 1, "Partial results should still be returned")
+	assert.Equal(t, "partial", result.Choices[0].Message.Content)
+}
+
+// TestCollectCompletionStream tests that text completion chunks are concatenated per choice
+func TestCollectCompletionStream(t *testing.T) {
+	body := "data: {\"id\":\"gen-2\",\"object\":\"text_completion\",\"choices\":[{\"index\":0,\"text\":\"Mmm, \"}]}\n\n" +
+		"data: {\"id\":\"gen-2\",\"choices\":[{\"index\":0,\"text\":\"donuts\",\"finish_reason\":\"stop\"}],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":2,\"total_tokens\":5}}\n\n" +
+		"data: [DONE]\n\n"
+
+	events := ReadStream[CompletionResponse](context.Background(), io.NopCloser(strings.NewReader(body)))
+	result, err := CollectCompletionStream(events, nil)
+	require.NoError(t, err, "CollectCompletionStream should not return an error")
+	require.Len(t, result.Choices, 1)
+	assert.Equal(t, "Mmm, donuts", result.Choices[0].Text)
+	assert.Equal(t, "stop", result.Choices[0].FinishReason)
+	require.NotNil(t, result.Usage)
+	assert.Equal(t, 5, result.Usage.TotalTokens)
+}
@@ -0,0 +1,154 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/kklipsch/billy-bot/pkg/jsonschema"
+	"github.com/rs/zerolog/log"
+)
+
+// StructuredOptions configures a Structured call
+type StructuredOptions struct {
+	// Name is the name given to the response format, "response" if empty
+	Name string
+	// Schema overrides the schema reflected from the result type
+	Schema *jsonschema.Schema
+	// RepairAttempts is how many times the model is shown its validation error and asked to try again
+	RepairAttempts int
+}
+
+// Structured sends a chat completion asking for output matching the JSON schema of T and decodes it.
+// The content of the first choice is extracted with ExtractJSON, validated against the schema and
+// unmarshaled into T. If that fails and repair attempts remain, the model is sent its answer along
+// with the error and asked to correct it.
+// The returned Response carries the decoded value in Result and the extracted JSON in Body.
+func Structured[T any](ctx context.Context, client *Client, request ChatCompletionRequest, opts StructuredOptions) Response[T] {
+	name := opts.Name
+	if name == "" {
+		name = "response"
+	}
+
+	schema := opts.Schema
+	if schema == nil {
+		schema = jsonschema.Reflect[T]()
+	}
+
+	if request.ResponseFormat == nil {
+		request.ResponseFormat = NewResponseFormatEnabled(name, schema)
+	}
+
+	messages := append([]ChatMessage(nil), request.Messages...)
+
+	for attempt := 0; ; attempt++ {
+		request.Messages = messages
+		result := client.ChatCompletion(ctx, request)
+		if result.Err != nil {
+			return Response[T]{Body: result.Body, Err: result.Err}
+		}
+
+		if len(result.Result.Choices) == 0 || result.Result.Choices[0].Message == nil {
+			return Response[T]{Body: result.Body, Err: fmt.Errorf("no choices in response")}
+		}
+
+		content := result.Result.Choices[0].Message.Content
+		value, err := DecodeStructured[T](content, schema)
+		if err == nil {
+			return Response[T]{Body: ExtractJSON(content), Result: value}
+		}
+
+		if attempt >= opts.RepairAttempts {
+			return Response[T]{Body: content, Err: err}
+		}
+
+		log.Info().Err(err).Int("attempt", attempt+1).Msg("structured output was invalid, asking the model to repair it")
+
+		messages = append(messages,
+			ChatMessage{Role: "assistant", Content: content},
+			ChatMessage{Role: "user", Content: fmt.Sprintf(
+				"Your response was not valid: %v\nRespond again with only the corrected JSON matching the schema.", err)},
+		)
+	}
+}
+
+// DecodeStructured extracts the JSON from a model's content, validates it against schema and
+// unmarshals it into T.
+func DecodeStructured[T any](content string, schema *jsonschema.Schema) (T, error) {
+	var value T
+
+	extracted := ExtractJSON(content)
+	if extracted == "" {
+		return value, fmt.Errorf("no JSON found in response content")
+	}
+
+	if !json.Valid([]byte(extracted)) {
+		return value, fmt.Errorf("response content is not valid JSON: %s", extracted)
+	}
+
+	if schema != nil {
+		if err := schema.Validate(json.RawMessage(extracted)); err != nil {
+			return value, fmt.Errorf("response does not match the schema: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(extracted), &value); err != nil {
+		return value, fmt.Errorf("error unmarshaling response content: %w", err)
+	}
+
+	return value, nil
+}
+
+// ExtractJSON returns the JSON document in a model's content.
+// Some models wrap structured output in markdown code fences or lead with prose such as
+// "Here are the quotes:", both of which are stripped. Prose can itself contain brackets, so each
+// [ or { is tried in turn and the first one that starts a valid document is used.
+func ExtractJSON(content string) string {
+	content = strings.TrimSpace(content)
+
+	// prefer the contents of a fenced block if there is one
+	if start := strings.Index(content, "```"); start >= 0 {
+		fenced := content[start+3:]
+		// drop the language tag, e.g. ```json
+		if newline := strings.IndexByte(fenced, '\n'); newline >= 0 {
+			fenced = fenced[newline+1:]
+		}
+		if end := strings.Index(fenced, "```"); end >= 0 {
+			fenced = fenced[:end]
+		}
+		content = strings.TrimSpace(fenced)
+	}
+
+	for offset := 0; ; {
+		start := strings.IndexAny(content[offset:], "[{")
+		if start < 0 {
+			break
+		}
+		start += offset
+
+		var document json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(content[start:])).Decode(&document); err == nil && json.Valid(document) {
+			return string(document)
+		}
+		offset = start + 1
+	}
+
+	// nothing is valid, return the likeliest document so the error explains what is wrong with it
+	start := strings.IndexAny(content, "[{")
+	if start < 0 {
+		return ""
+	}
+
+	closer := "}"
+	if content[start] == '[' {
+		closer = "]"
+	}
+
+	end := strings.LastIndex(content, closer)
+	if end < start {
+		return strings.TrimSpace(content[start:])
+	}
+
+	return content[start : end+1]
+}
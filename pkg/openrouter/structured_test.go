@@ -0,0 +1,97 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kklipsch/billy-bot/pkg/jsonschema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestExtractJSON tests stripping code fences and prose from model content
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "Plain", content: `[{"quote":"d'oh"}]`, expected: `[{"quote":"d'oh"}]`},
+		{name: "Fenced", content: "```json\n[{\"quote\":\"d'oh\"}]\n```", expected: `[{"quote":"d'oh"}]`},
+		{name: "Leading prose", content: "Here are the quotes:\n{\"quote\":\"d'oh\"}\nEnjoy!", expected: `{"quote":"d'oh"}`},
+		{name: "Prose and fence", content: "Sure!\n```\n[1, 2]\n```\nAnything else?", expected: `[1, 2]`},
+		{name: "Bracketed prose", content: "Here are [some] quotes: [{\"quote\":\"d'oh\"}]", expected: `[{"quote":"d'oh"}]`},
+		{name: "Invalid", content: "Here you go: [{\"quote\":\"d'oh\",}]", expected: `[{"quote":"d'oh",}]`},
+		{name: "No JSON", content: "I can't help with that.", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExtractJSON(tt.content))
+		})
+	}
+}
+
+// TestStructuredRepair tests that invalid output is sent back to the model to be corrected
+func TestStructuredRepair(t *testing.T) {
+	type quote struct {
+		Quote      string  `json:"quote"`
+		Confidence float64 `json:"confidence" jsonschema:"minimum=0,maximum=1"`
+	}
+
+	answers := []string{
+		"```json\n[{\"quote\":\"d'oh\",\"confidence\":7.3}]\n```",
+		`[{"quote":"d'oh","confidence":0.73}]`,
+	}
+
+	var requests []ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+
+		content, _ := json.Marshal(answers[len(requests)-1])
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s}}]}`, content)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+
+	result := Structured[[]quote](context.Background(), client, ChatCompletionRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "classy"}},
+	}, StructuredOptions{Name: "quotes", RepairAttempts: 1})
+	require.NoError(t, result.Err, "The repaired output should be accepted")
+	assert.Equal(t, []quote{{Quote: "d'oh", Confidence: 0.73}}, result.Result)
+
+	require.Len(t, requests, 2)
+	require.NotNil(t, requests[0].ResponseFormat, "The response format should be set from the type")
+	assert.Equal(t, "json_schema", requests[0].ResponseFormat.Type)
+	require.NotNil(t, requests[0].ResponseFormat.JSONSchema)
+	assert.Equal(t, "quotes", requests[0].ResponseFormat.JSONSchema.Name, "The response format should be sent with its name")
+	assert.True(t, requests[0].ResponseFormat.JSONSchema.Strict)
+	repair := requests[1].Messages
+	require.Len(t, repair, 3)
+	assert.Equal(t, "assistant", repair[1].Role)
+	assert.Contains(t, repair[2].Content, "/0/confidence: must be <= 1", "The validation error should be sent to the model")
+}
+
+// TestResponseFormatShape tests that the response format is nested the way OpenRouter expects
+// and leaves the shared schema untouched
+func TestResponseFormatShape(t *testing.T) {
+	schema := jsonschema.Reflect[[]string]()
+
+	body, err := json.Marshal(ChatCompletionRequest{ResponseFormat: NewResponseFormatEnabled("quotes", schema)})
+	require.NoError(t, err)
+
+	var sent map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(body, &sent))
+	assert.NotContains(t, sent, "type", "The response format should not be flattened into the request")
+	assert.NotContains(t, sent, "json_schema", "The response format should not be flattened into the request")
+	assert.JSONEq(t, `{"type":"json_schema","json_schema":{"name":"quotes","strict":true,"schema":{"type":"array","items":{"type":"string"}}}}`, string(sent["response_format"]))
+	assert.False(t, schema.Strict, "The schema should not be modified")
+}
@@ -0,0 +1,168 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMaxToolIterations is the number of tool calling rounds a ToolRunner allows by default
+const DefaultMaxToolIterations = 5
+
+// ErrMaxToolIterations is returned when the model is still calling tools after the iteration limit
+var ErrMaxToolIterations = errors.New("model kept calling tools after the maximum number of iterations")
+
+// ToolHandler executes a single tool call.
+// It receives the raw JSON arguments sent by the model and returns the content of the
+// tool message sent back to it.
+type ToolHandler func(ctx context.Context, arguments string) (string, error)
+
+// ToolRunner executes the tool calls a model makes and feeds the results back to it
+// until the model answers without calling a tool.
+// See https://openrouter.ai/docs/features/tool-calling for the message flow.
+type ToolRunner struct {
+	// MaxIterations bounds how many times the model may call tools before Run gives up
+	MaxIterations int
+
+	tools    []Tool
+	handlers map[string]ToolHandler
+}
+
+// NewToolRunner creates an empty ToolRunner with the default iteration limit
+func NewToolRunner() *ToolRunner {
+	return &ToolRunner{
+		MaxIterations: DefaultMaxToolIterations,
+		handlers:      make(map[string]ToolHandler),
+	}
+}
+
+// Register adds a tool described by function with a handler working on the raw arguments.
+// Registering a name twice replaces the earlier tool.
+func (r *ToolRunner) Register(function Function, handler ToolHandler) {
+	tool := Tool{Type: "function", Function: function}
+
+	if _, ok := r.handlers[function.Name]; ok {
+		for i := range r.tools {
+			if r.tools[i].Function.Name == function.Name {
+				r.tools[i] = tool
+			}
+		}
+	} else {
+		r.tools = append(r.tools, tool)
+	}
+
+	r.handlers[function.Name] = handler
+}
+
+// RegisterTool adds a tool whose arguments are decoded into A before the handler is called.
+// The handler result is sent to the model as is when it is a string and as JSON otherwise.
+func RegisterTool[A any](r *ToolRunner, function Function, handler func(ctx context.Context, args A) (any, error)) {
+	r.Register(function, func(ctx context.Context, arguments string) (string, error) {
+		var args A
+		if arguments != "" {
+			if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+				return "", fmt.Errorf("error decoding arguments for %s: %w", function.Name, err)
+			}
+		}
+
+		result, err := handler(ctx, args)
+		if err != nil {
+			return "", err
+		}
+
+		if s, ok := result.(string); ok {
+			return s, nil
+		}
+
+		content, err := json.Marshal(result)
+		if err != nil {
+			return "", fmt.Errorf("error encoding result of %s: %w", function.Name, err)
+		}
+		return string(content), nil
+	})
+}
+
+// Tools returns the definitions of the registered tools for use in a request
+func (r *ToolRunner) Tools() []Tool {
+	return r.tools
+}
+
+// Run sends request and keeps executing the tools the model calls, appending their results as
+// "tool" messages and re-sending, until the model responds without tool calls.
+// It returns the final response along with the full conversation, including the tool messages.
+// The registered tools are added to the request if it does not list any.
+func (r *ToolRunner) Run(ctx context.Context, client *Client, request ChatCompletionRequest) (Response[ChatCompletionResponse], []ChatMessage) {
+	if len(request.Tools) == 0 {
+		request.Tools = r.Tools()
+	}
+
+	messages := append([]ChatMessage(nil), request.Messages...)
+
+	for iteration := 0; ; iteration++ {
+		request.Messages = messages
+		result := client.ChatCompletion(ctx, request)
+		if result.Err != nil {
+			return result, messages
+		}
+
+		if len(result.Result.Choices) == 0 || result.Result.Choices[0].Message == nil {
+			return result, messages
+		}
+
+		message := result.Result.Choices[0].Message
+		if len(message.ToolCalls) == 0 {
+			return result, append(messages, ChatMessage{Role: message.Role, Content: message.Content})
+		}
+
+		if iteration >= r.MaxIterations {
+			result.Err = fmt.Errorf("%w (%d)", ErrMaxToolIterations, r.MaxIterations)
+			return result, messages
+		}
+
+		messages = append(messages, ChatMessage{
+			Role:      "assistant",
+			Content:   message.Content,
+			ToolCalls: message.ToolCalls,
+		})
+
+		for _, call := range message.ToolCalls {
+			messages = append(messages, r.execute(ctx, call))
+		}
+	}
+}
+
+// execute runs a single tool call and builds the tool message answering it.
+// Failures are reported to the model rather than aborting the run so it can correct itself.
+func (r *ToolRunner) execute(ctx context.Context, call ToolCall) ChatMessage {
+	reply := ChatMessage{Role: "tool", ToolCallID: call.ID}
+
+	if call.Function == nil {
+		reply.Content = "error: tool call did not include a function"
+		return reply
+	}
+	reply.Name = call.Function.Name
+
+	logger := log.With().Str("tool", call.Function.Name).Str("tool_call_id", call.ID).Logger()
+
+	handler, ok := r.handlers[call.Function.Name]
+	if !ok {
+		logger.Warn().Msg("model called an unknown tool")
+		reply.Content = fmt.Sprintf("error: unknown tool %q", call.Function.Name)
+		return reply
+	}
+
+	logger.Debug().Str("arguments", call.Function.Arguments).Msg("executing tool call")
+
+	content, err := handler(ctx, call.Function.Arguments)
+	if err != nil {
+		logger.Warn().Err(err).Msg("tool call failed")
+		reply.Content = fmt.Sprintf("error: %v", err)
+		return reply
+	}
+
+	reply.Content = content
+	return reply
+}
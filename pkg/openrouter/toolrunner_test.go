@@ -0,0 +1,65 @@
+package openrouter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestToolRunner tests that tool calls are executed and their results sent back until the model answers
+func TestToolRunner(t *testing.T) {
+	requests := []ChatCompletionRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+
+		if len(requests) == 1 {
+			w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"","tool_calls":[
+				{"index":0,"id":"call_1","type":"function","function":{"name":"frinkiac","arguments":"{\"quote\":\"classy\"}"}},
+				{"index":1,"id":"call_2","type":"function","function":{"name":"missing","arguments":"{}"}}
+			]}}]}`))
+			return
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"looking very classy"}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+
+	type args struct {
+		Quote string `json:"quote"`
+	}
+
+	runner := NewToolRunner()
+	RegisterTool(runner, Function{Name: "frinkiac", Description: "search frinkiac"}, func(_ context.Context, a args) (any, error) {
+		return map[string]string{"found": a.Quote}, nil
+	})
+
+	result, messages := runner.Run(context.Background(), client, ChatCompletionRequest{
+		Model:    "test/model",
+		Messages: []ChatMessage{{Role: "user", Content: "classy"}},
+	})
+	require.NoError(t, result.Err, "Run should not return an error")
+	assert.Equal(t, "looking very classy", result.Result.Choices[0].Message.Content)
+
+	require.Len(t, requests, 2, "The model should be called again with the tool results")
+	require.Len(t, requests[0].Tools, 1, "The registered tools should be sent")
+	assert.Equal(t, "frinkiac", requests[0].Tools[0].Function.Name)
+
+	sent := requests[1].Messages
+	require.Len(t, sent, 4, "The user, assistant and two tool messages should be sent")
+	assert.Equal(t, "assistant", sent[1].Role)
+	assert.Len(t, sent[1].ToolCalls, 2)
+	assert.Equal(t, ChatMessage{Role: "tool", ToolCallID: "call_1", Name: "frinkiac", Content: `{"found":"classy"}`}, sent[2])
+	assert.Equal(t, "call_2", sent[3].ToolCallID)
+	assert.Contains(t, sent[3].Content, "unknown tool", "Unknown tools should be reported to the model")
+
+	assert.Len(t, messages, 5, "The returned conversation should include the final answer")
+}
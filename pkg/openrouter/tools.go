@@ -1,5 +1,9 @@
 package openrouter
 
+import (
+	"github.com/kklipsch/billy-bot/pkg/jsonschema"
+)
+
 // ToolsEnabled provides configuration for enabling AI model tool usage capabilities.
 // While not documented in the main API reference as of 2025-05-19, this functionality
 // is described in https://openrouter.ai/docs/features/tool-calling.
@@ -16,26 +20,20 @@ type Tool struct {
 }
 
 // Function describes a callable function that the AI model can use.
-// It includes the function name, description, arguments, and parameter specifications.
+// It includes the function name, description, arguments, and the JSON schema of its parameters.
 type Function struct {
-	Name        string     `json:"name"`
-	Description string     `json:"description"`
-	Arguments   string     `json:"arguments"`
-	Parameters  Parameters `json:"parameters"`
-}
-
-// Parameters defines the structure of function parameters for a tool.
-// It specifies the parameter type, properties, and which properties are required.
-type Parameters struct {
-	Type       string              `json:"type"`
-	Properties map[string]Property `json:"properties"`
-	Required   []string            `json:"required,omitempty"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Arguments   string             `json:"arguments,omitempty"`
+	Parameters  *jsonschema.Schema `json:"parameters,omitempty"`
 }
 
-// Property defines a single property within a function parameter.
-// It includes the property type, description, and optional enumeration of allowed values.
-type Property struct {
-	Type        string   `json:"type"`
-	Description string   `json:"description"`
-	Enum        []string `json:"enum,omitempty"`
+// NewFunction creates a Function whose parameters are described by the schema reflected from A,
+// the type its arguments are decoded into.
+func NewFunction[A any](name, description string) Function {
+	return Function{
+		Name:        name,
+		Description: description,
+		Parameters:  jsonschema.Reflect[A](),
+	}
 }
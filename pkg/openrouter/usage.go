@@ -0,0 +1,212 @@
+package openrouter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// UsageRecord is the usage reported for a single call
+type UsageRecord struct {
+	GenerationID string        `json:"generation_id,omitempty"`
+	Model        string        `json:"model,omitempty"`
+	Provider     string        `json:"provider,omitempty"`
+	Usage        UsageResponse `json:"usage"`
+	// Generation holds the authoritative counts from the generation endpoint, if they were looked up
+	Generation *Generation `json:"generation,omitempty"`
+}
+
+// usageReporter is implemented by the responses that carry usage
+type usageReporter interface {
+	usageRecord() (UsageRecord, bool)
+}
+
+// usageRecord returns the usage of a chat completion, false if it has none
+func (r ChatCompletionResponse) usageRecord() (UsageRecord, bool) {
+	if r.Usage == nil {
+		return UsageRecord{}, false
+	}
+	return UsageRecord{GenerationID: r.ID, Model: r.Model, Provider: r.Provider, Usage: *r.Usage}, true
+}
+
+// usageRecord returns the usage of a text completion, false if it has none
+func (r CompletionResponse) usageRecord() (UsageRecord, bool) {
+	if r.Usage == nil {
+		return UsageRecord{}, false
+	}
+	return UsageRecord{GenerationID: r.ID, Model: r.Model, Provider: r.Provider, Usage: *r.Usage}, true
+}
+
+// UsageTracker collects the usage of every call made by a Client, e.g. over a command run.
+// It is safe for concurrent use.
+type UsageTracker struct {
+	mu      sync.Mutex
+	records []UsageRecord
+}
+
+// NewUsageTracker creates an empty UsageTracker
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{}
+}
+
+// Record adds the usage of a call
+func (t *UsageTracker) Record(record UsageRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.records = append(t.records, record)
+}
+
+// Records returns a copy of the recorded usage in the order the calls completed
+func (t *UsageTracker) Records() []UsageRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]UsageRecord(nil), t.records...)
+}
+
+// LookupGenerations fetches the generation for each record that has an id but no generation yet.
+// Failures are logged and the record is left as reported in the response.
+func (t *UsageTracker) LookupGenerations(ctx context.Context, client *Client) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, record := range t.records {
+		if record.GenerationID == "" || record.Generation != nil {
+			continue
+		}
+
+		generation, err := client.GetGeneration(ctx, record.GenerationID)
+		if err != nil {
+			log.Warn().Err(err).Str("generation_id", record.GenerationID).Msg("unable to look up generation")
+			continue
+		}
+		t.records[i].Generation = &generation
+	}
+}
+
+// Summary totals the recorded usage.
+// Where a generation was looked up its native token counts and cost are used instead of the response's.
+func (t *UsageTracker) Summary() UsageSummary {
+	summary := UsageSummary{Calls: t.Records()}
+
+	for _, record := range summary.Calls {
+		if g := record.Generation; g != nil {
+			summary.PromptTokens += g.NativeTokensPrompt
+			summary.CompletionTokens += g.NativeTokensCompletion
+			summary.ReasoningTokens += g.NativeTokensReasoning
+			summary.CachedTokens += g.NativeTokensCached
+			summary.Cost += g.TotalCost
+			continue
+		}
+
+		summary.PromptTokens += record.Usage.PromptTokens
+		summary.CompletionTokens += record.Usage.CompletionTokens
+		summary.ReasoningTokens += record.Usage.ReasoningTokens()
+		summary.CachedTokens += record.Usage.CachedTokens()
+		summary.Cost += record.Usage.Cost
+	}
+
+	return summary
+}
+
+// UsageSummary is the total usage of a set of calls
+type UsageSummary struct {
+	PromptTokens     int           `json:"prompt_tokens"`
+	CompletionTokens int           `json:"completion_tokens"`
+	ReasoningTokens  int           `json:"reasoning_tokens"`
+	CachedTokens     int           `json:"cached_tokens"`
+	Cost             float64       `json:"cost"`
+	Calls            []UsageRecord `json:"calls"`
+}
+
+// WriteText writes a short human readable summary to w
+func (s UsageSummary) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "usage: %d calls, %d prompt tokens (%d cached), %d completion tokens (%d reasoning), $%.6f\n",
+		len(s.Calls), s.PromptTokens, s.CachedTokens, s.CompletionTokens, s.ReasoningTokens, s.Cost)
+	return err
+}
+
+// recordUsage adds the usage of a response, if it reported any, to the client's tracker and budget
+func (c *Client) recordUsage(response usageReporter) {
+	record, ok := response.usageRecord()
+	if !ok {
+		return
+	}
+
+	if c.Usage != nil {
+		c.Usage.Record(record)
+	}
+	c.Budget.record(record)
+}
+
+// trackStream records the usage carried by the final chunks of a stream as they pass through.
+// It stops forwarding when ctx is done, like the stream it wraps, so a consumer that stops reading doesn't leak it.
+func trackStream[T usageReporter](ctx context.Context, c *Client, events <-chan StreamEvent[T]) <-chan StreamEvent[T] {
+	if c.Usage == nil && c.Budget == nil {
+		return events
+	}
+
+	tracked := make(chan StreamEvent[T])
+	go func() {
+		defer close(tracked)
+		for event := range events {
+			if event.Err == nil {
+				c.recordUsage(event.Chunk)
+			}
+			select {
+			case tracked <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return tracked
+}
+
+// Generation is the stored record of a call from the generation endpoint.
+// Based on https://openrouter.ai/docs/api-reference/get-a-generation as of 2025-06-09.
+type Generation struct {
+	ID                     string  `json:"id"`
+	TotalCost              float64 `json:"total_cost"`
+	CreatedAt              string  `json:"created_at,omitempty"`
+	Model                  string  `json:"model,omitempty"`
+	Origin                 string  `json:"origin,omitempty"`
+	Usage                  float64 `json:"usage,omitempty"`
+	IsBYOK                 bool    `json:"is_byok,omitempty"`
+	UpstreamID             string  `json:"upstream_id,omitempty"`
+	CacheDiscount          float64 `json:"cache_discount,omitempty"`
+	Streamed               bool    `json:"streamed,omitempty"`
+	Cancelled              bool    `json:"cancelled,omitempty"`
+	ProviderName           string  `json:"provider_name,omitempty"`
+	Latency                int     `json:"latency,omitempty"`
+	GenerationTime         int     `json:"generation_time,omitempty"`
+	FinishReason           string  `json:"finish_reason,omitempty"`
+	NativeFinishReason     string  `json:"native_finish_reason,omitempty"`
+	TokensPrompt           int     `json:"tokens_prompt,omitempty"`
+	TokensCompletion       int     `json:"tokens_completion,omitempty"`
+	NativeTokensPrompt     int     `json:"native_tokens_prompt,omitempty"`
+	NativeTokensCompletion int     `json:"native_tokens_completion,omitempty"`
+	NativeTokensReasoning  int     `json:"native_tokens_reasoning,omitempty"`
+	NativeTokensCached     int     `json:"native_tokens_cached,omitempty"`
+}
+
+// generationResponse is the response from the generation endpoint
+type generationResponse struct {
+	Data Generation `json:"data"`
+}
+
+// GetGeneration fetches the native token counts and cost of a call from the generation endpoint.
+// The generation may take a moment to become available after the call completes.
+func (c *Client) GetGeneration(ctx context.Context, id string) (Generation, error) {
+	result := Get[generationResponse](ctx, c, "generation?id="+url.QueryEscape(id))
+	if result.Err != nil {
+		return Generation{}, fmt.Errorf("error getting generation %s: %w", id, result.Err)
+	}
+	return result.Result.Data, nil
+}
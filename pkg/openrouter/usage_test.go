@@ -0,0 +1,108 @@
+package openrouter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestUsageTracker tests that usage is requested, recorded for plain and streamed calls and totaled
+func TestUsageTracker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/generation" {
+			if r.URL.Query().Get("id") != "gen-1" {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error":{"code":404,"message":"Generation not found"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"data":{"id":"gen-1","total_cost":0.002,"native_tokens_prompt":12,"native_tokens_completion":4,"native_tokens_reasoning":1}}`)
+			return
+		}
+
+		var request ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		require.NotNil(t, request.Usage, "Usage accounting should always be requested")
+		assert.True(t, request.Usage.Include)
+
+		if request.Stream {
+			fmt.Fprint(w, "data: {\"id\":\"gen-2\",\"choices\":[{\"index\":0,\"delta\":{\"content\":\"d'oh\"}}]}\n\n"+
+				"data: {\"id\":\"gen-2\",\"model\":\"test/model\",\"choices\":[],\"usage\":{\"prompt_tokens\":5,\"completion_tokens\":2,\"total_tokens\":7,\"cost\":0.0005}}\n\n"+
+				"data: [DONE]\n\n")
+			return
+		}
+
+		fmt.Fprint(w, `{"id":"gen-1","model":"test/model","provider":"OpenAI","choices":[{"message":{"role":"assistant","content":"d'oh"}}],
+			"usage":{"prompt_tokens":10,"completion_tokens":3,"total_tokens":13,"cost":0.001,
+			"prompt_tokens_details":{"cached_tokens":6},"completion_tokens_details":{"reasoning_tokens":1}}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-key")
+	client.BaseURL = server.URL
+	client.Usage = NewUsageTracker()
+
+	request := ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}
+
+	result := client.ChatCompletion(context.Background(), request)
+	require.NoError(t, result.Err)
+
+	events, err := client.StreamChatCompletion(context.Background(), request)
+	require.NoError(t, err)
+	_, err = CollectStream(events, nil)
+	require.NoError(t, err)
+
+	summary := client.Usage.Summary()
+	require.Len(t, summary.Calls, 2, "Both calls should be recorded")
+	assert.Equal(t, "gen-1", summary.Calls[0].GenerationID)
+	assert.Equal(t, 15, summary.PromptTokens)
+	assert.Equal(t, 5, summary.CompletionTokens)
+	assert.Equal(t, 6, summary.CachedTokens)
+	assert.Equal(t, 1, summary.ReasoningTokens)
+	assert.InDelta(t, 0.0015, summary.Cost, 1e-9)
+
+	client.Usage.LookupGenerations(context.Background(), client)
+	summary = client.Usage.Summary()
+	require.NotNil(t, summary.Calls[0].Generation, "The generation should be looked up")
+	assert.Nil(t, summary.Calls[1].Generation, "A missing generation should keep the reported usage")
+	assert.Equal(t, 17, summary.PromptTokens, "The native counts should replace the reported ones")
+	assert.InDelta(t, 0.0025, summary.Cost, 1e-9)
+
+	var text bytes.Buffer
+	require.NoError(t, summary.WriteText(&text))
+	assert.Equal(t, "usage: 2 calls, 17 prompt tokens (0 cached), 6 completion tokens (1 reasoning), $0.002500\n", text.String())
+}
+
+// TestTrackStreamCancelled tests that a tracked stream closes once cancelled even if nothing reads it
+func TestTrackStreamCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	events := make(chan StreamEvent[ChatCompletionResponse], 1)
+	events <- StreamEvent[ChatCompletionResponse]{Chunk: ChatCompletionResponse{ID: "gen-1"}}
+	close(events)
+
+	client := NewClient("test-key")
+	client.Usage = NewUsageTracker()
+	tracked := trackStream(ctx, client, events)
+
+	// a non-blocking receive only succeeds if the tracker is blocked sending or has closed the stream
+	sent := false
+	require.Eventually(t, func() bool {
+		select {
+		case _, ok := <-tracked:
+			sent = ok
+			return true
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond, "The stream should close once the context is cancelled")
+	assert.False(t, sent, "The tracker should not block sending after the context is cancelled")
+}